@@ -2,6 +2,12 @@ module github.com/aniaan/sous-chef
 
 go 1.25.5
 
-require golang.org/x/mod v0.31.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/klauspost/compress v1.20.1
+	github.com/ulikunitz/xz v0.5.15
+	golang.org/x/crypto v0.50.0
+	golang.org/x/mod v0.31.0
+)
 
-require github.com/ulikunitz/xz v0.5.15 // indirect
+require golang.org/x/sys v0.43.0 // indirect
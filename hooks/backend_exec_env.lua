@@ -1,7 +1,45 @@
+-- has_entry reports whether dir is one of the colon-separated entries of list
+local function has_entry(list, dir)
+  for entry in string.gmatch(list or "", "[^:]+") do
+    if entry == dir then
+      return true
+    end
+  end
+  return false
+end
+
 function PLUGIN:BackendExecEnv(ctx)
+  local file = require("file")
+  local sc = require("lib")
+  local env_vars = {
+    { key = "PATH", value = ctx.install_path .. "/bin" }
+  }
+
+  -- mise merges only PATH across tools; every other variable takes the last
+  -- tool's value. MANPATH and FPATH therefore point at the share directory
+  -- every install links its man pages and completions into, so each tool
+  -- sets the same value.
+  local share_dir = sc.share_dir()
+
+  -- Skip directories already present, e.g. when the hook runs in a shell
+  -- that activated mise before, so the variables don't grow on every run
+  local man_dir = share_dir .. "/man"
+  local manpath = os.getenv("MANPATH")
+  if file.exists(man_dir) and not has_entry(manpath, man_dir) then
+    -- A trailing colon keeps man's default search path
+    table.insert(env_vars, { key = "MANPATH", value = man_dir .. ":" .. (manpath or "") })
+  end
+
+  local zsh_dir = share_dir .. "/zsh/site-functions"
+  local fpath = os.getenv("FPATH")
+  if file.exists(zsh_dir) and not has_entry(fpath, zsh_dir) then
+    if fpath and fpath ~= "" then
+      zsh_dir = zsh_dir .. ":" .. fpath
+    end
+    table.insert(env_vars, { key = "FPATH", value = zsh_dir })
+  end
+
   return {
-    env_vars = {
-      { key = "PATH", value = ctx.install_path .. "/bin" }
-    }
+    env_vars = env_vars
   }
 end
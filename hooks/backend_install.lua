@@ -7,7 +7,15 @@ function PLUGIN:BackendInstall(ctx)
 
   local bin = sc.get_binary()
 
-  local command = string.format("%s install --tool %s --version %s --dir %s", bin, tool, version, install_path)
+  -- Passed through the environment, which binaries without --share-dir ignore
+  local command = string.format(
+    "SOUS_CHEF_SHARE_DIR=%s %s install --tool %s --version %s --dir %s",
+    sc.share_dir(),
+    bin,
+    tool,
+    version,
+    install_path
+  )
 
   cmd.exec(command)
 
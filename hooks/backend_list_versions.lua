@@ -1,16 +1,23 @@
 function PLUGIN:BackendListVersions(ctx)
   local cmd = require("cmd")
+  local json = require("json")
   local sc = require("lib")
   local tool = ctx.tool
 
   local bin = sc.get_binary()
 
-  local command = string.format("%s list-versions --tool %s", bin, tool)
-  local stdout = cmd.exec(command)
-
   local versions = {}
-  for line in stdout:gmatch("[^\r\n]+") do
-    table.insert(versions, line)
+  if sc.supports_output(bin) then
+    local command = string.format("%s list-versions --tool %s --output json", bin, tool)
+    for _, record in ipairs(json.decode(cmd.exec(command))) do
+      table.insert(versions, record.version)
+    end
+  else
+    -- Older binaries print one version per line
+    local command = string.format("%s list-versions --tool %s", bin, tool)
+    for line in cmd.exec(command):gmatch("[^\r\n]+") do
+      table.insert(versions, line)
+    end
   end
 
   return { versions = versions }
@@ -0,0 +1,114 @@
+// Package auth discovers API tokens for GitHub, GitLab and Gitea hosts from
+// environment variables, the sous-chef config file, mise, the gh CLI and netrc.
+package auth
+
+import (
+	"fmt"
+	"os"
+)
+
+// DefaultHost is the host of public GitHub
+const DefaultHost = "github.com"
+
+// Credential is a token and a description of where it was found. Source
+// never contains the token itself.
+type Credential struct {
+	Token  string
+	Source string
+}
+
+// Provider finds a token for a host such as github.com
+type Provider interface {
+	// Name describes the source, e.g. "GH_TOKEN" or "gh CLI"
+	Name() string
+	// Token returns the token for host and the place it was read from, or
+	// "" if the provider has none
+	Token(host string) (token, from string, err error)
+}
+
+// Chain tries providers in order
+type Chain []Provider
+
+// DefaultChain is the lookup order used by the GitHub client: environment
+// variables, the sous-chef config file, mise, the gh CLI, then netrc
+func DefaultChain() Chain {
+	return Chain{
+		envProvider{name: "GITHUB_TOKEN", scope: scopeGitHub},
+		envProvider{name: "GH_TOKEN", scope: scopeGitHub},
+		envProvider{name: "GH_ENTERPRISE_TOKEN", scope: scopeEnterprise},
+		envProvider{name: "GITHUB_ENTERPRISE_TOKEN", scope: scopeEnterprise},
+		configProvider{},
+		miseProvider{},
+		ghProvider{},
+		netrcProvider{},
+	}
+}
+
+// GitLabChain is the lookup order for GitLab hosts: GITLAB_TOKEN, the
+// sous-chef config file, then netrc
+func GitLabChain() Chain {
+	return Chain{
+		envProvider{name: "GITLAB_TOKEN", scope: scopeAny},
+		configProvider{},
+		netrcProvider{},
+	}
+}
+
+// GiteaChain is the lookup order for Gitea and Forgejo hosts such as
+// codeberg.org: GITEA_TOKEN, FORGEJO_TOKEN, the sous-chef config file, then
+// netrc
+func GiteaChain() Chain {
+	return Chain{
+		envProvider{name: "GITEA_TOKEN", scope: scopeAny},
+		envProvider{name: "FORGEJO_TOKEN", scope: scopeAny},
+		configProvider{},
+		netrcProvider{},
+	}
+}
+
+// Lookup returns the first credential found for host. Providers that fail
+// are reported on stderr and skipped.
+func (c Chain) Lookup(host string) (Credential, bool) {
+	for _, p := range c {
+		token, from, err := p.Token(host)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", p.Name(), err)
+			continue
+		}
+		if token == "" {
+			continue
+		}
+		source := p.Name()
+		if from != "" {
+			source = fmt.Sprintf("%s (%s)", source, from)
+		}
+		return Credential{Token: token, Source: source}, true
+	}
+	return Credential{}, false
+}
+
+// envScope selects the hosts an environment variable applies to
+type envScope int
+
+const (
+	scopeAny        envScope = iota
+	scopeGitHub              // github.com only
+	scopeEnterprise          // Every host but github.com
+)
+
+// envProvider reads a token from an environment variable. Like the gh CLI,
+// GH_TOKEN and GITHUB_TOKEN only apply to github.com and the *_ENTERPRISE_TOKEN
+// variables to every other host.
+type envProvider struct {
+	name  string
+	scope envScope
+}
+
+func (p envProvider) Name() string { return p.name }
+
+func (p envProvider) Token(host string) (string, string, error) {
+	if (p.scope == scopeGitHub && host != DefaultHost) || (p.scope == scopeEnterprise && host == DefaultHost) {
+		return "", "", nil
+	}
+	return os.Getenv(p.name), "", nil
+}
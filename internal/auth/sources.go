@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/aniaan/sous-chef/internal/config"
+)
+
+// ghTokenTimeout bounds `gh auth token`, which may unlock a keyring
+const ghTokenTimeout = 5 * time.Second
+
+// configProvider reads the [tokens] table of the sous-chef config file
+type configProvider struct{}
+
+func (configProvider) Name() string { return "sous-chef config" }
+
+func (configProvider) Token(host string) (string, string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", "", err
+	}
+	path, _ := config.Path()
+	return cfg.Tokens[host], path, nil
+}
+
+// miseProvider reads MISE_GITHUB_TOKEN or the github_token setting of the
+// global mise config
+type miseProvider struct{}
+
+func (miseProvider) Name() string { return "mise" }
+
+func (miseProvider) Token(host string) (string, string, error) {
+	if host != DefaultHost {
+		return "", "", nil
+	}
+	if token := os.Getenv("MISE_GITHUB_TOKEN"); token != "" {
+		return token, "MISE_GITHUB_TOKEN", nil
+	}
+
+	path := os.Getenv("MISE_GLOBAL_CONFIG_FILE")
+	if path == "" {
+		dir, err := configHome("MISE_CONFIG_DIR", "mise")
+		if err != nil {
+			return "", "", nil
+		}
+		path = filepath.Join(dir, "config.toml")
+	}
+	var cfg struct {
+		Settings struct {
+			GitHubToken string `toml:"github_token"`
+		} `toml:"settings"`
+	}
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+	return cfg.Settings.GitHubToken, path, nil
+}
+
+// ghProvider reads the gh CLI's hosts.yml, falling back to `gh auth token`
+// for tokens kept in the system keyring
+type ghProvider struct{}
+
+func (ghProvider) Name() string { return "gh CLI" }
+
+func (ghProvider) Token(host string) (string, string, error) {
+	if dir, err := configHome("GH_CONFIG_DIR", "gh"); err == nil {
+		path := filepath.Join(dir, "hosts.yml")
+		if token := hostsYAMLToken(path, host); token != "" {
+			return token, path, nil
+		}
+	}
+
+	bin, err := exec.LookPath("gh")
+	if err != nil {
+		return "", "", nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ghTokenTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, bin, "auth", "token", "--hostname", host).Output()
+	if err != nil {
+		// Not logged in to host
+		return "", "", nil
+	}
+	return strings.TrimSpace(string(out)), "gh auth token", nil
+}
+
+// hostsYAMLToken extracts the active account's token from a gh hosts.yml:
+// hosts.<host>.oauth_token, or hosts.<host>.users.<user>.oauth_token for the
+// account named by hosts.<host>.user. Tokens of other accounts are ignored.
+// Only the block layout gh writes is understood, so no YAML library is needed.
+func hostsYAMLToken(path, host string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var (
+		inHost     bool
+		hostIndent int // Indentation of the host's own keys, once seen
+		inUsers    bool
+		userIndent int // Indentation of the account names under users
+		account    string
+		token      string
+		active     string
+		userTokens = make(map[string]string)
+	)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == 0 {
+			inHost = unquote(strings.TrimSuffix(trimmed, ":")) == host
+			hostIndent, inUsers = 0, false
+			continue
+		}
+		if !inHost {
+			continue
+		}
+
+		key, value, _ := strings.Cut(trimmed, ":")
+		key, value = unquote(strings.TrimSpace(key)), unquote(strings.TrimSpace(value))
+		if hostIndent == 0 {
+			hostIndent = indent
+		}
+		switch {
+		case indent == hostIndent:
+			inUsers, userIndent = key == "users", 0
+			switch key {
+			case "oauth_token":
+				token = value
+			case "user":
+				active = value
+			}
+		case inUsers:
+			if userIndent == 0 {
+				userIndent = indent
+			}
+			if indent == userIndent {
+				account = key
+			} else if key == "oauth_token" && account != "" {
+				userTokens[account] = value
+			}
+		}
+	}
+	if token != "" {
+		return token
+	}
+	return userTokens[active]
+}
+
+// netrcProvider reads the password for api.<host> or <host> from ~/.netrc,
+// or the file named by NETRC
+type netrcProvider struct{}
+
+func (netrcProvider) Name() string { return "netrc" }
+
+func (netrcProvider) Token(host string) (string, string, error) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", nil
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	machines := parseNetrc(string(data))
+	for _, name := range []string{"api." + host, host} {
+		if password := machines[name]; password != "" {
+			return password, path, nil
+		}
+	}
+	return "", "", nil
+}
+
+// parseNetrc maps machine names to passwords. Macros and the default entry
+// are ignored.
+func parseNetrc(data string) map[string]string {
+	machines := map[string]string{}
+	fields := strings.Fields(data)
+	machine := ""
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			if i+1 < len(fields) {
+				machine = fields[i+1]
+				i++
+			}
+		case "default":
+			machine = ""
+		case "macdef":
+			// A macro runs to the next blank line, which Fields can't see; stop here
+			return machines
+		case "login", "account":
+			i++
+		case "password":
+			if i+1 < len(fields) && machine != "" {
+				machines[machine] = fields[i+1]
+			}
+			i++
+		}
+	}
+	return machines
+}
+
+// configHome returns $<env>, $XDG_CONFIG_HOME/<name> or ~/.config/<name>
+func configHome(env, name string) (string, error) {
+	if dir := os.Getenv(env); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, name), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", name), nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
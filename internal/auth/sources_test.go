@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHostsYAMLToken(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		host string
+		want string
+	}{
+		{
+			name: "single account",
+			yaml: "github.com:\n    oauth_token: gho_a\n    user: alice\n    git_protocol: https\n",
+			host: "github.com",
+			want: "gho_a",
+		},
+		{
+			name: "multiple accounts, active one listed last",
+			yaml: "github.com:\n    users:\n        alice:\n            oauth_token: gho_a\n        bob:\n            oauth_token: gho_b\n    git_protocol: https\n    user: bob\n",
+			host: "github.com",
+			want: "gho_b",
+		},
+		{
+			name: "multiple accounts, active one listed first",
+			yaml: "github.com:\n    user: alice\n    users:\n        alice:\n            oauth_token: gho_a\n        bob:\n            oauth_token: gho_b\n",
+			host: "github.com",
+			want: "gho_a",
+		},
+		{
+			name: "host token wins over account tokens",
+			yaml: "github.com:\n    users:\n        alice:\n            oauth_token: gho_a\n    oauth_token: gho_host\n    user: alice\n",
+			host: "github.com",
+			want: "gho_host",
+		},
+		{
+			name: "account tokens without an active user",
+			yaml: "github.com:\n    users:\n        alice:\n            oauth_token: gho_a\n",
+			host: "github.com",
+		},
+		{
+			name: "other host",
+			yaml: "ghe.example.com:\n    oauth_token: gho_e\ngithub.com:\n    oauth_token: 'gho_q'\n",
+			host: "github.com",
+			want: "gho_q",
+		},
+		{
+			name: "missing host",
+			yaml: "ghe.example.com:\n    oauth_token: gho_e\n",
+			host: "github.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hosts.yml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if got := hostsYAMLToken(path, tt.host); got != tt.want {
+				t.Errorf("hostsYAMLToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
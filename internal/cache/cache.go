@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aniaan/sous-chef/internal/util"
+)
+
+const (
+	// DirEnv overrides the cache root directory
+	DirEnv = "SOUS_CHEF_CACHE_DIR"
+	// TTLEnv overrides the metadata TTL (Go duration, e.g. "30m")
+	TTLEnv = "SOUS_CHEF_CACHE_TTL"
+
+	// DefaultTTL is how long cached metadata is used without revalidation
+	DefaultTTL = time.Hour
+
+	metadataDir = "releases"
+)
+
+// Dir returns the cache root directory
+func Dir() (string, error) {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return dir, nil
+	}
+	return util.CacheDir()
+}
+
+// Entry is a cached API response
+type Entry struct {
+	URL       string          `json:"url"`
+	ETag      string          `json:"etag,omitempty"`
+	Link      string          `json:"link,omitempty"` // Link header, used for pagination
+	FetchedAt time.Time       `json:"fetched_at"`
+	Body      json.RawMessage `json:"body"`
+}
+
+// Metadata stores GitHub API responses on disk, one directory per repo
+type Metadata struct {
+	dir string
+	TTL time.Duration
+}
+
+// OpenMetadata opens the release metadata cache under Dir.
+// The TTL is read from SOUS_CHEF_CACHE_TTL, falling back to DefaultTTL.
+func OpenMetadata() (*Metadata, error) {
+	root, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := DefaultTTL
+	if v := os.Getenv(TTLEnv); v != "" {
+		if ttl, err = time.ParseDuration(v); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Metadata{dir: filepath.Join(root, metadataDir), TTL: ttl}, nil
+}
+
+// Fresh reports whether the entry can be used without revalidation
+func (m *Metadata) Fresh(e *Entry) bool {
+	return time.Since(e.FetchedAt) < m.TTL
+}
+
+// Get returns the cached entry for url, or nil if there is none
+func (m *Metadata) Get(repo, url string) (*Entry, error) {
+	path, err := m.path(repo, url)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil || e.URL != url {
+		// Corrupt or colliding entry: treat as a miss
+		return nil, nil
+	}
+	return &e, nil
+}
+
+// Put stores an entry for repo
+func (m *Metadata) Put(repo string, e *Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	path, err := m.path(repo, e.URL)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+func (m *Metadata) path(repo, url string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(repo)) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidKey, repo)
+	}
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(m.dir, filepath.FromSlash(repo), hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// Usage describes the contents of a cache area
+type Usage struct {
+	Path    string
+	Entries int
+	Bytes   int64
+}
+
+// Info returns usage for each cache area
+func Info() ([]Usage, error) {
+	root, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	var usage []Usage
+	for _, name := range []string{metadataDir, downloadsDir} {
+		u, err := dirUsage(filepath.Join(root, name))
+		if err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, nil
+}
+
+// Clear removes all cached data
+func Clear() error {
+	root, err := Dir()
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{metadataDir, downloadsDir} {
+		if err := os.RemoveAll(filepath.Join(root, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dirUsage(dir string) (Usage, error) {
+	u := Usage{Path: dir}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		u.Entries++
+		u.Bytes += info.Size()
+		return nil
+	})
+	return u, err
+}
+
+// writeFileAtomic writes data to a temp file next to path and renames it into place
+func writeFileAtomic(path string, data []byte) (err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,282 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aniaan/sous-chef/internal/lock"
+)
+
+const (
+	// MaxSizeEnv overrides the download cache size limit (bytes, or with a K/M/G suffix)
+	MaxSizeEnv = "SOUS_CHEF_DOWNLOAD_CACHE_MAX"
+
+	// DefaultMaxSize is the default download cache size limit
+	DefaultMaxSize = 1 << 30
+
+	downloadsDir = "downloads"
+)
+
+// ErrInvalidKey is returned for cache entries whose repo, tag or filename
+// would place them outside the cache directory
+var ErrInvalidKey = errors.New("invalid cache key")
+
+// Downloads is a content-addressed cache of release assets.
+// Blobs are stored by SHA-256 under blobs/, and index/<repo>/<tag>/<filename>
+// records which digest an asset resolved to. Blob mtimes track last use for
+// LRU eviction.
+type Downloads struct {
+	dir      string
+	MaxBytes int64
+}
+
+// OpenDownloads opens the download cache under Dir.
+// The size limit is read from SOUS_CHEF_DOWNLOAD_CACHE_MAX, falling back to DefaultMaxSize.
+func OpenDownloads() (*Downloads, error) {
+	root, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	maxBytes := int64(DefaultMaxSize)
+	if v := os.Getenv(MaxSizeEnv); v != "" {
+		if maxBytes, err = parseSize(v); err != nil {
+			return nil, fmt.Errorf("%s: %w", MaxSizeEnv, err)
+		}
+	}
+
+	return &Downloads{dir: filepath.Join(root, downloadsDir), MaxBytes: maxBytes}, nil
+}
+
+// Lookup returns the path and digest of a cached asset. The blob is re-hashed
+// and only returned if it still matches the recorded digest.
+func (d *Downloads) Lookup(repo, tag, filename string) (string, string, bool) {
+	digest, ok := d.Digest(repo, tag, filename)
+	if !ok {
+		return "", "", false
+	}
+
+	indexPath, err := d.indexPath(repo, tag, filename)
+	if err != nil {
+		return "", "", false
+	}
+	blob := d.blobPath(digest)
+	actual, err := FileSHA256(blob)
+	if err != nil || actual != digest {
+		// Missing or corrupt blob: drop both so the asset is downloaded again
+		os.Remove(blob)
+		os.Remove(indexPath)
+		return "", "", false
+	}
+
+	now := time.Now()
+	os.Chtimes(blob, now, now)
+	return blob, digest, true
+}
+
+// Digest returns the digest recorded for a cached asset, which was verified
+// against its published checksum when stored. The blob itself is not checked.
+func (d *Downloads) Digest(repo, tag, filename string) (string, bool) {
+	indexPath, err := d.indexPath(repo, tag, filename)
+	if err != nil {
+		return "", false
+	}
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
+
+// Store copies src into the cache under digest, which must be the verified
+// SHA-256 of src, then evicts least recently used blobs over the size limit.
+func (d *Downloads) Store(repo, tag, filename, src, digest string) error {
+	indexPath, err := d.indexPath(repo, tag, filename)
+	if err != nil {
+		return err
+	}
+	blob := d.blobPath(digest)
+	if err := copyVerified(src, blob, digest); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(indexPath, []byte(digest+"\n")); err != nil {
+		return err
+	}
+	return d.Evict()
+}
+
+// Evict removes least recently used blobs until the cache fits MaxBytes
+func (d *Downloads) Evict() error {
+	if d.MaxBytes <= 0 {
+		return nil
+	}
+
+	type blobInfo struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	entries, err := os.ReadDir(filepath.Join(d.dir, "blobs"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var blobs []blobInfo
+	var total int64
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		blobs = append(blobs, blobInfo{
+			path:    filepath.Join(d.dir, "blobs", e.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+		total += info.Size()
+	}
+
+	sort.Slice(blobs, func(i, j int) bool {
+		return blobs[i].modTime.Before(blobs[j].modTime)
+	})
+	for _, b := range blobs {
+		if total <= d.MaxBytes {
+			break
+		}
+		if err := os.Remove(b.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		total -= b.size
+	}
+	return nil
+}
+
+// Lock takes the lock for a cache entry. Holding it across lookup, download
+// and Store keeps concurrent installs from fetching the same asset twice.
+func (d *Downloads) Lock(repo, tag, filename string, timeout time.Duration) (*lock.Lock, error) {
+	rel, err := entryPath(repo, tag, filename)
+	if err != nil {
+		return nil, err
+	}
+	return lock.Acquire(filepath.Join(d.dir, "locks", rel+".lock"), timeout)
+}
+
+// PartialPath returns where an in-progress download of an asset is kept, so an
+// interrupted download can be resumed by a later install
+func (d *Downloads) PartialPath(repo, tag, filename string) (string, error) {
+	rel, err := entryPath(repo, tag, filename)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d.dir, "partial", rel), nil
+}
+
+func (d *Downloads) blobPath(digest string) string {
+	return filepath.Join(d.dir, "blobs", digest)
+}
+
+func (d *Downloads) indexPath(repo, tag, filename string) (string, error) {
+	rel, err := entryPath(repo, tag, filename)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d.dir, "index", rel), nil
+}
+
+// entryPath returns <repo>/<tag>/<filename> relative to a cache area. Tags and
+// asset names come from forges and registries, so each part must stay local.
+func entryPath(repo, tag, filename string) (string, error) {
+	for _, part := range []string{repo, tag, filename} {
+		if !filepath.IsLocal(filepath.FromSlash(part)) {
+			return "", fmt.Errorf("%w: %s/%s@%s", ErrInvalidKey, repo, filename, tag)
+		}
+	}
+	return filepath.Join(filepath.FromSlash(repo), filepath.FromSlash(tag), filename), nil
+}
+
+// FileSHA256 returns the hex-encoded SHA-256 of a file
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// copyVerified copies src to dst atomically, failing if the content does not hash to digest
+func copyVerified(src, dst, digest string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.CreateTemp(filepath.Dir(dst), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(out.Name())
+		}
+	}()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(out, hasher), in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(hasher.Sum(nil)); actual != digest {
+		return fmt.Errorf("digest mismatch: expected %s, got %s", digest, actual)
+	}
+	return os.Rename(out.Name(), dst)
+}
+
+// parseSize parses a byte count with an optional K, M or G suffix
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B")
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		mult = 1 << 30
+	}
+	if mult != 1 {
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * mult, nil
+}
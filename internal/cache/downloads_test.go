@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDownloadsRejectsKeysOutsideCache(t *testing.T) {
+	tests := []struct {
+		name                string
+		repo, tag, filename string
+	}{
+		{"dot-dot tag", "owner/repo", "../../../escape", "tool.tar.gz"},
+		{"dot-dot filename", "owner/repo", "v1.0.0", "../../../../escape"},
+		{"dot-dot repo", "../../escape", "v1.0.0", "tool.tar.gz"},
+		{"absolute filename", "owner/repo", "v1.0.0", "/tmp/escape"},
+		{"empty tag", "owner/repo", "", "tool.tar.gz"},
+	}
+
+	root := t.TempDir()
+	d := &Downloads{dir: filepath.Join(root, "cache")}
+	src := filepath.Join(root, "asset")
+	if err := os.WriteFile(src, []byte("asset"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	digest, err := FileSHA256(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := d.PartialPath(tt.repo, tt.tag, tt.filename); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("PartialPath() error = %v, want %v", err, ErrInvalidKey)
+			}
+			if _, err := d.Lock(tt.repo, tt.tag, tt.filename, 0); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Lock() error = %v, want %v", err, ErrInvalidKey)
+			}
+			if err := d.Store(tt.repo, tt.tag, tt.filename, src, digest); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Store() error = %v, want %v", err, ErrInvalidKey)
+			}
+			if _, _, ok := d.Lookup(tt.repo, tt.tag, tt.filename); ok {
+				t.Error("Lookup() found an entry outside the cache")
+			}
+		})
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() != "cache" && e.Name() != "asset" {
+			t.Errorf("%s written outside the cache", e.Name())
+		}
+	}
+}
+
+func TestDownloadsStoreLookup(t *testing.T) {
+	root := t.TempDir()
+	d := &Downloads{dir: filepath.Join(root, "cache"), MaxBytes: DefaultMaxSize}
+	src := filepath.Join(root, "asset")
+	if err := os.WriteFile(src, []byte("asset"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	digest, err := FileSHA256(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tags may contain slashes, as in monorepo tags
+	if err := d.Store("owner/repo", "tool/v1.0.0", "tool.tar.gz", src, digest); err != nil {
+		t.Fatal(err)
+	}
+	blob, got, ok := d.Lookup("owner/repo", "tool/v1.0.0", "tool.tar.gz")
+	if !ok || got != digest || !strings.HasPrefix(blob, d.dir) {
+		t.Errorf("Lookup() = %s, %s, %v; want a blob with digest %s", blob, got, ok, digest)
+	}
+	if err := d.Store("owner/repo", "v1.0.0", "tool.tar.gz", src, "0000"); err == nil {
+		t.Error("Store() accepted a wrong digest")
+	}
+}
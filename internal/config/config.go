@@ -0,0 +1,78 @@
+// Package config reads the sous-chef configuration file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/aniaan/sous-chef/internal/util"
+)
+
+// PathEnv overrides the location of the configuration file
+const PathEnv = "SOUS_CHEF_CONFIG"
+
+// Config is the contents of config.toml
+type Config struct {
+	// Tokens maps a host (github.com, an Enterprise host, gitlab.com,
+	// codeberg.org, ...) to an API token
+	Tokens map[string]string `toml:"tokens"`
+
+	// Mirrors rewrite GitHub API and download URLs, tried in order
+	Mirrors []Mirror `toml:"mirrors"`
+
+	// CABundle is a PEM file of CA certificates trusted in addition to the
+	// system roots
+	CABundle string `toml:"ca_bundle"`
+
+	// LocalMirror is a directory of release assets laid out as
+	// <repo>/<tag>/<filename>, used instead of downloading them
+	LocalMirror string `toml:"local_mirror"`
+}
+
+// Mirror is a URL rewrite rule, e.g. https://github.com/* -> https://mirror.corp/github/*
+type Mirror struct {
+	From string `toml:"from"`
+	To   string `toml:"to"`
+}
+
+// Path returns the path of the configuration file
+func Path() (string, error) {
+	if path := os.Getenv(PathEnv); path != "" {
+		return path, nil
+	}
+	dir, err := util.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.toml"), nil
+}
+
+// Load reads the configuration file. A missing file yields an empty Config.
+func Load() (*Config, error) {
+	cfg := &Config{}
+	path, err := Path()
+	if err != nil {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && os.Getenv(PathEnv) == "" {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	md, err := toml.Decode(string(data), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%s: unknown key %q", path, undecoded[0].String())
+	}
+	return cfg, nil
+}
@@ -2,11 +2,18 @@ package gh
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/aniaan/sous-chef/internal/auth"
+	"github.com/aniaan/sous-chef/internal/cache"
 )
 
 type Release struct {
@@ -23,64 +30,300 @@ type Asset struct {
 	Digest             string `json:"digest"` // Custom field, optional
 }
 
-const githubAPIBaseURL = "https://api.github.com"
+const (
+	// DefaultAPIBaseURL is the REST API root of public GitHub
+	DefaultAPIBaseURL = "https://api.github.com"
+	// DefaultWebBaseURL is the web root of public GitHub, which serves release downloads
+	DefaultWebBaseURL = "https://github.com"
+
+	// enterpriseAPIPath is where GitHub Enterprise Server mounts the REST API
+	enterpriseAPIPath = "/api/v3"
+	apiVersion        = "2022-11-28"
+)
+
+// UserAgent is sent with every request; main sets it to include the version
+var UserAgent = "sous-chef"
+
+const (
+	// DefaultMaxPages is the default cap on release pages fetched by ListReleases
+	DefaultMaxPages = 10
+	releasesPerPage = 100
+)
 
 // Client is a simple GitHub API client
 type Client struct {
-	httpClient *http.Client
+	httpClient     *http.Client
+	downloadClient *http.Client
+
+	// Cache, if set, stores API responses on disk
+	Cache *cache.Metadata
+
+	// MaxPages caps the number of release pages ListReleases follows (0 = DefaultMaxPages)
+	MaxPages int
+
+	// MaxRetries caps download retries after the first attempt (0 = DefaultMaxRetries)
+	MaxRetries int
+	// Progress, if set, receives download progress
+	Progress Progress
+
+	// IdleTimeout aborts a download attempt when no data arrives for this long (0 = DefaultIdleTimeout)
+	IdleTimeout time.Duration
+
+	// WaitOnRateLimit sleeps until the rate limit resets instead of failing
+	WaitOnRateLimit bool
+
+	// Credentials finds the API token (nil = auth.DefaultChain)
+	Credentials auth.Chain
+
+	// APIBaseURL is the REST API root, e.g. https://ghe.example.com/api/v3.
+	// Empty derives it from WebBaseURL, or uses DefaultAPIBaseURL.
+	APIBaseURL string
+	// WebBaseURL is the root release assets are downloaded from, e.g.
+	// https://ghe.example.com. Empty derives it from APIBaseURL, or uses
+	// DefaultWebBaseURL.
+	WebBaseURL string
+
+	// Mirrors rewrite API and download URLs, first match wins. Tokens are
+	// only sent when the rewritten URL keeps the original host; mirror
+	// credentials can be given as user:password@ in the mirror URL.
+	Mirrors []Mirror
+
+	// Offline answers API requests from the metadata cache alone, however
+	// old the entries are, and fails with ErrOffline instead of sending any
+	// request
+	Offline bool
+	// LocalMirror is a directory of release assets laid out as
+	// <repo>/<tag>/<filename>, used instead of downloading them
+	LocalMirror string
+
+	mu              sync.Mutex
+	rateLimit       RateLimit
+	rateLimitWarned bool
+	instances       map[string]*Client // Clients for other instances, by base URLs
+
+	credentialOnce sync.Once
+	credential     auth.Credential
+	hasCredential  bool
 }
 
 func NewClient() *Client {
 	return &Client{
+		// Own transports, so LoadCABundle doesn't touch http.DefaultTransport.
+		// Both honor HTTPS_PROXY, HTTP_PROXY and NO_PROXY.
 		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
+			Timeout:   60 * time.Second,
+		},
+		// No whole-request timeout: large assets on slow links are bounded by
+		// IdleTimeout between reads instead
+		downloadClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				TLSHandshakeTimeout:   30 * time.Second,
+				ResponseHeaderTimeout: 60 * time.Second,
+			},
 		},
 	}
 }
 
-func (c *Client) newRequest(method, url string) (*http.Request, error) {
-	req, err := http.NewRequest(method, url, nil)
-	if err != nil {
-		return nil, err
-	}
+// Credential returns the token used for requests and where it came from,
+// looking it up on first use
+func (c *Client) Credential() (auth.Credential, bool) {
+	c.credentialOnce.Do(func() {
+		chain := c.Credentials
+		if chain == nil {
+			chain = auth.DefaultChain()
+		}
+		c.credential, c.hasCredential = chain.Lookup(c.Host())
+	})
+	return c.credential, c.hasCredential
+}
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+// apiBaseURL returns APIBaseURL, derived from WebBaseURL if unset
+func (c *Client) apiBaseURL() string {
+	switch {
+	case c.APIBaseURL != "":
+		return strings.TrimSuffix(c.APIBaseURL, "/")
+	case c.WebBaseURL != "" && strings.TrimSuffix(c.WebBaseURL, "/") != DefaultWebBaseURL:
+		return strings.TrimSuffix(c.WebBaseURL, "/") + enterpriseAPIPath
+	default:
+		return DefaultAPIBaseURL
+	}
+}
 
-	token := os.Getenv("GITHUB_TOKEN")
-	if token != "" {
-		req.Header.Set("Authorization", "token "+token)
+// webBaseURL returns WebBaseURL, derived from APIBaseURL if unset
+func (c *Client) webBaseURL() string {
+	if c.WebBaseURL != "" {
+		return strings.TrimSuffix(c.WebBaseURL, "/")
 	}
+	api := c.apiBaseURL()
+	if api == DefaultAPIBaseURL {
+		return DefaultWebBaseURL
+	}
+	if web, ok := strings.CutSuffix(api, enterpriseAPIPath); ok {
+		return web
+	}
+	// api.<host> layout, as used by GHE.com; anything else serves both
+	if u, err := url.Parse(api); err == nil {
+		if host, ok := strings.CutPrefix(u.Host, "api."); ok {
+			u.Host = host
+			return u.String()
+		}
+	}
+	return api
+}
 
-	return req, nil
+// Host returns the GitHub host credentials are looked up for, e.g.
+// github.com or ghe.example.com
+func (c *Client) Host() string {
+	u, err := url.Parse(c.webBaseURL())
+	if err != nil || u.Host == "" {
+		return auth.DefaultHost
+	}
+	return u.Host
 }
 
-// GetReleaseByTag fetches a specific release by tag
-func (c *Client) GetReleaseByTag(repo, tag string) (*Release, error) {
-	url := fmt.Sprintf("%s/repos/%s/releases/tags/%s", githubAPIBaseURL, repo, tag)
+// WithBaseURLs returns a client for another GitHub instance that shares this
+// client's HTTP clients, cache and settings. It returns c itself when both
+// URLs are empty or already c's. Clients are reused per instance, so rate
+// limits and credentials are tracked once per host.
+func (c *Client) WithBaseURLs(apiBaseURL, webBaseURL string) *Client {
+	if apiBaseURL == "" && webBaseURL == "" {
+		return c
+	}
+	other := &Client{APIBaseURL: apiBaseURL, WebBaseURL: webBaseURL}
+	api, web := other.apiBaseURL(), other.webBaseURL()
+	if api == c.apiBaseURL() && web == c.webBaseURL() {
+		return c
+	}
 
-	req, err := c.newRequest("GET", url)
-	if err != nil {
-		return nil, err
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := api + " " + web
+	if existing, ok := c.instances[key]; ok {
+		return existing
 	}
+	if c.instances == nil {
+		c.instances = make(map[string]*Client)
+	}
+	other = &Client{
+		httpClient:      c.httpClient,
+		downloadClient:  c.downloadClient,
+		Cache:           c.Cache,
+		MaxPages:        c.MaxPages,
+		MaxRetries:      c.MaxRetries,
+		Progress:        c.Progress,
+		IdleTimeout:     c.IdleTimeout,
+		WaitOnRateLimit: c.WaitOnRateLimit,
+		Credentials:     c.Credentials,
+		Mirrors:         c.Mirrors,
+		Offline:         c.Offline,
+		LocalMirror:     c.LocalMirror,
+		APIBaseURL:      api,
+		WebBaseURL:      web,
+	}
+	c.instances[key] = other
+	return other
+}
 
-	resp, err := c.httpClient.Do(req)
+// newRequest creates a request for url, or for its mirror if one matches.
+// Callers keep using the upstream URL as the cache key. The GitHub token is
+// only sent to this client's instance, and not when header brings its own
+// Authorization. Offline clients fail with ErrOffline.
+func (c *Client) newRequest(method, url string, header http.Header) (*http.Request, error) {
+	if c.Offline {
+		return nil, fmt.Errorf("%w: cannot fetch %s", ErrOffline, url)
+	}
+	target := c.rewriteURL(url)
+	req, err := http.NewRequest(method, target, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("github api returned status: %s", resp.Status)
+	req.Header.Set("User-Agent", UserAgent)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if req.Header.Get("Authorization") == "" && c.ownsURL(url) && !c.Mirrored(url) {
+		if cred, ok := c.Credential(); ok {
+			req.Header.Set("Authorization", "Bearer "+cred.Token)
+		}
 	}
 
+	return req, nil
+}
+
+// apiHeader pins REST API requests to apiVersion
+var apiHeader = http.Header{
+	"Accept":               {"application/vnd.github+json"},
+	"X-Github-Api-Version": {apiVersion},
+}
+
+// newAPIRequest creates a REST API request
+func (c *Client) newAPIRequest(method, url string) (*http.Request, error) {
+	return c.newRequest(method, url, apiHeader)
+}
+
+// ownsURL reports whether rawURL points at this client's GitHub instance
+func (c *Client) ownsURL(rawURL string) bool {
+	return sameHost(rawURL, c.apiBaseURL()) || sameHost(rawURL, c.webBaseURL())
+}
+
+// GetReleaseByTag fetches a specific release by tag
+func (c *Client) GetReleaseByTag(repo, tag string) (*Release, error) {
+	return c.getReleaseByTag(repo, tag, false)
+}
+
+// OptionalReleaseByTag is GetReleaseByTag for callers that can do without the
+// release. It returns ErrRateLimitReserved instead of spending one of the last
+// RateLimitReserve requests.
+func (c *Client) OptionalReleaseByTag(repo, tag string) (*Release, error) {
+	return c.getReleaseByTag(repo, tag, true)
+}
+
+func (c *Client) getReleaseByTag(repo, tag string, optional bool) (*Release, error) {
+	url := fmt.Sprintf("%s/repos/%s/releases/tags/%s", c.apiBaseURL(), repo, tag)
+
 	var release Release
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+	_, err := c.fetchJSON(repo, url, apiHeader, &release, optional)
+	if errors.Is(err, ErrOffline) {
+		// Listing releases caches the same data, digests included
+		if listed, ok := c.cachedListedRelease(repo, tag); ok {
+			return listed, nil
+		}
+	}
+	if err != nil {
 		return nil, err
 	}
 
 	return &release, nil
 }
 
+// cachedListedRelease looks for tag in the cached pages of repo's release list
+func (c *Client) cachedListedRelease(repo, tag string) (*Release, bool) {
+	if c.Cache == nil {
+		return nil, false
+	}
+	url := c.releasesURL(repo)
+	for page := 0; url != "" && page < c.PageLimit(); page++ {
+		entry, _ := c.Cache.Get(repo, url)
+		if entry == nil {
+			break
+		}
+		var releases []Release
+		if err := json.Unmarshal(entry.Body, &releases); err != nil {
+			break
+		}
+		for i := range releases {
+			if releases[i].TagName == tag {
+				return &releases[i], true
+			}
+		}
+		url = NextPageURL(entry.Link)
+	}
+	return nil, false
+}
+
 // GetAssetChecksum fetches the checksum (digest) for a specific asset if available
 // Returns empty string if not found or no digest present
 func (c *Client) GetAssetChecksum(repo, tag, filename string) (string, error) {
@@ -88,71 +331,218 @@ func (c *Client) GetAssetChecksum(repo, tag, filename string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	return release.AssetDigest(filename), nil
+}
 
-	for _, asset := range release.Assets {
-		if asset.Name == filename {
-			if asset.Digest != "" {
-				return asset.Digest[7:], nil
-			}
-			return "", nil
+// AssetDigest returns the hex SHA-256 the API reports for an asset, or "" if
+// the asset is missing or has no digest
+func (r *Release) AssetDigest(filename string) string {
+	for _, asset := range r.Assets {
+		if asset.Name != filename {
+			continue
 		}
+		if digest, ok := strings.CutPrefix(asset.Digest, "sha256:"); ok {
+			return digest
+		}
+		return ""
 	}
-	return "", nil // Asset not found or no digest
+	return ""
 }
 
-// ListReleases fetches the latest releases for a repository
-func (c *Client) ListReleases(repo string) ([]Release, error) {
-	url := fmt.Sprintf("%s/repos/%s/releases", githubAPIBaseURL, repo)
+// GetAttestations fetches the Sigstore bundles of GitHub artifact attestations
+// for an asset digest (hex SHA-256). It returns ErrNotFound if there are none.
+func (c *Client) GetAttestations(repo, digest string) ([]json.RawMessage, error) {
+	url := fmt.Sprintf("%s/repos/%s/attestations/sha256:%s", c.apiBaseURL(), repo, digest)
 
-	req, err := c.newRequest("GET", url)
-	if err != nil {
-		return nil, err
+	var resp struct {
+		Attestations []struct {
+			Bundle json.RawMessage `json:"bundle"`
+		} `json:"attestations"`
 	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
+	if _, err := c.getJSON(repo, url, &resp); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("github api returned status: %s", resp.Status)
+	bundles := make([]json.RawMessage, 0, len(resp.Attestations))
+	for _, a := range resp.Attestations {
+		bundles = append(bundles, a.Bundle)
+	}
+	if len(bundles) == 0 {
+		return nil, ErrNotFound
 	}
+	return bundles, nil
+}
+
+// ListReleases fetches releases for a repository, newest first, following
+// pagination. After each page, more is called with every release fetched so
+// far; returning false stops pagination early. A nil more fetches all pages,
+// up to MaxPages.
+func (c *Client) ListReleases(repo string, more func([]Release) bool) ([]Release, error) {
+	url := c.releasesURL(repo)
 
 	var releases []Release
-	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
-		return nil, err
+	for page := 0; url != "" && page < c.PageLimit(); page++ {
+		batch, next, err := c.listReleasesPage(repo, url)
+		if page > 0 && errors.Is(err, ErrOffline) {
+			// Later pages are only cached if an online listing needed them
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, batch...)
+		if more != nil && !more(releases) {
+			break
+		}
+		url = next
 	}
 
 	return releases, nil
 }
 
-// DownloadReleaseAsset downloads a release asset to a destination path
-func (c *Client) DownloadReleaseAsset(repo, tag, filename, destPath string) error {
-	url := fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", repo, tag, filename)
+// releasesURL returns the URL of the first page of repo's releases
+func (c *Client) releasesURL(repo string) string {
+	return fmt.Sprintf("%s/repos/%s/releases?per_page=%d", c.apiBaseURL(), repo, releasesPerPage)
+}
+
+// listReleasesPage fetches a single page of releases and returns the URL of the next page, if any
+func (c *Client) listReleasesPage(repo, url string) ([]Release, string, error) {
+	var releases []Release
+	link, err := c.getJSON(repo, url, &releases)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return releases, NextPageURL(link), nil
+}
+
+// PageLimit returns MaxPages, or DefaultMaxPages if unset
+func (c *Client) PageLimit() int {
+	if c.MaxPages <= 0 {
+		return DefaultMaxPages
+	}
+	return c.MaxPages
+}
+
+// getJSON fetches url and decodes the JSON response into v, returning the
+// Link header. Responses are served from and stored in the metadata cache
+// when one is configured; stale entries are revalidated with If-None-Match.
+func (c *Client) getJSON(repo, url string, v any) (string, error) {
+	return c.fetchJSON(repo, url, apiHeader, v, false)
+}
 
-	req, err := c.newRequest("GET", url)
+// GetJSON is getJSON for other forges' APIs: it sends header instead of the
+// GitHub API headers, and caches the response under key
+func (c *Client) GetJSON(key, url string, header http.Header, v any) (string, error) {
+	return c.fetchJSON(key, url, header, v, false)
+}
+
+// GetText is GetJSON for responses that are not JSON, such as version lists
+// and HTML indexes
+func (c *Client) GetText(key, url string, header http.Header) (string, error) {
+	var text rawText
+	_, err := c.fetchJSON(key, url, header, &text, false)
+	return string(text), err
+}
+
+// rawText makes fetchJSON store a response body as a JSON string, so any body
+// fits in the metadata cache
+type rawText string
+
+// fetchJSON implements getJSON. Optional requests fail with
+// ErrRateLimitReserved when the rate limit is nearly exhausted, unless the
+// cache can answer them or revalidate for free. Offline, any cached entry is
+// used and a miss fails with ErrOffline.
+func (c *Client) fetchJSON(repo, url string, header http.Header, v any, optional bool) (string, error) {
+	var entry *cache.Entry
+	if c.Cache != nil {
+		entry, _ = c.Cache.Get(repo, url)
+		if entry != nil && (c.Offline || c.Cache.Fresh(entry)) {
+			return entry.Link, json.Unmarshal(entry.Body, v)
+		}
+	}
+	if c.Offline {
+		return "", fmt.Errorf("%w: %s is not in the metadata cache", ErrOffline, url)
+	}
+	// 304 responses to conditional requests don't count against the limit
+	if optional && (entry == nil || entry.ETag == "") && c.RateLimitNearlyExhausted() {
+		return "", ErrRateLimitReserved
+	}
+
+	for attempt := 0; ; attempt++ {
+		link, err := c.doGetJSON(repo, url, header, entry, v)
+		if c.waitForReset(err, attempt) {
+			continue
+		}
+		return link, err
+	}
+}
+
+// doGetJSON performs one request for fetchJSON, revalidating entry if set
+func (c *Client) doGetJSON(repo, url string, header http.Header, entry *cache.Entry, v any) (string, error) {
+	req, err := c.newRequest("GET", url, header)
 	if err != nil {
-		return err
+		return "", err
+	}
+	if entry != nil && entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed: %s", resp.Status)
+	// Other forges' limits are not GitHub's
+	if c.ownsURL(url) {
+		c.observeRateLimit(resp)
 	}
 
-	out, err := os.Create(destPath)
-	if err != nil {
-		return err
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		entry.FetchedAt = time.Now()
+	case resp.StatusCode == http.StatusOK:
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := v.(*rawText); ok {
+			body, _ = json.Marshal(string(body))
+		}
+		entry = &cache.Entry{
+			URL:       url,
+			ETag:      resp.Header.Get("ETag"),
+			Link:      resp.Header.Get("Link"),
+			FetchedAt: time.Now(),
+			Body:      body,
+		}
+	default:
+		return "", responseError(resp)
 	}
-	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
-	return err
+	if err := json.Unmarshal(entry.Body, v); err != nil {
+		return "", err
+	}
+	if c.Cache != nil {
+		if err := c.Cache.Put(repo, entry); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to write metadata cache: %v\n", err)
+		}
+	}
+	return entry.Link, nil
 }
 
+// NextPageURL extracts the rel="next" URL from a Link header
+func NextPageURL(link string) string {
+	for part := range strings.SplitSeq(link, ",") {
+		segments := strings.Split(part, ";")
+		if len(segments) < 2 {
+			continue
+		}
+		for _, param := range segments[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(segments[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+package gh
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aniaan/sous-chef/internal/auth"
+	"github.com/aniaan/sous-chef/internal/cache"
+)
+
+func TestBaseURLs(t *testing.T) {
+	tests := []struct {
+		name             string
+		apiBase, webBase string
+		wantAPI, wantWeb string
+	}{
+		{"github.com", "", "", "https://api.github.com", "https://github.com"},
+		{"enterprise web URL", "", "https://ghe.example.com", "https://ghe.example.com/api/v3", "https://ghe.example.com"},
+		{"enterprise API URL", "https://ghe.example.com/api/v3", "", "https://ghe.example.com/api/v3", "https://ghe.example.com"},
+		{"trailing slash", "https://ghe.example.com/api/v3/", "https://ghe.example.com/", "https://ghe.example.com/api/v3", "https://ghe.example.com"},
+		{"api subdomain", "https://api.acme.ghe.com", "", "https://api.acme.ghe.com", "https://acme.ghe.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				requests []string
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				requests = append(requests, r.Host+r.URL.Path)
+				mu.Unlock()
+				if strings.Contains(r.URL.Path, "/releases/tags/") {
+					fmt.Fprint(w, `{"tag_name":"v1.0.0"}`)
+					return
+				}
+				fmt.Fprint(w, "asset")
+			}))
+			defer srv.Close()
+			target, err := url.Parse(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := NewClient()
+			c.APIBaseURL, c.WebBaseURL = tt.apiBase, tt.webBase
+			c.Credentials = auth.Chain{}
+			c.httpClient = &http.Client{Transport: rewriteTransport{target: target}}
+			c.downloadClient = c.httpClient
+
+			wantAsset := tt.wantWeb + "/owner/tool/releases/download/v1.0.0/tool.tar.gz"
+			if got := c.apiBaseURL(); got != tt.wantAPI {
+				t.Errorf("apiBaseURL() = %s, want %s", got, tt.wantAPI)
+			}
+			if got := c.webBaseURL(); got != tt.wantWeb {
+				t.Errorf("webBaseURL() = %s, want %s", got, tt.wantWeb)
+			}
+			if got := c.AssetURL("owner/tool", "v1.0.0", "tool.tar.gz"); got != wantAsset {
+				t.Errorf("AssetURL() = %s, want %s", got, wantAsset)
+			}
+
+			if _, err := c.GetReleaseByTag("owner/tool", "v1.0.0"); err != nil {
+				t.Fatalf("GetReleaseByTag() error = %v", err)
+			}
+			if err := c.DownloadReleaseAsset("owner/tool", "v1.0.0", "tool.tar.gz", filepath.Join(t.TempDir(), "tool.tar.gz")); err != nil {
+				t.Fatalf("DownloadReleaseAsset() error = %v", err)
+			}
+			want := []string{
+				strings.TrimPrefix(tt.wantAPI, "https://") + "/repos/owner/tool/releases/tags/v1.0.0",
+				strings.TrimPrefix(wantAsset, "https://"),
+			}
+			if fmt.Sprint(requests) != fmt.Sprint(want) {
+				t.Errorf("requested %q, want %q", requests, want)
+			}
+		})
+	}
+}
+
+// releasesServer serves two pages of releases for owner/repo
+func releasesServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/owner/repo/releases?per_page=100&page=2>; rel="next"`, srv.URL))
+			fmt.Fprint(w, `[{"tag_name":"v2.0.0","assets":[{"name":"tool.tar.gz","digest":"sha256:abc"}]}]`)
+		case "2":
+			fmt.Fprint(w, `[{"tag_name":"v1.0.0"}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// cachedClient returns a client for srv with an empty metadata cache
+func cachedClient(t *testing.T, srv *httptest.Server) *Client {
+	t.Helper()
+	t.Setenv(cache.DirEnv, t.TempDir())
+	metadata, err := cache.OpenMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient()
+	c.APIBaseURL = srv.URL
+	c.Cache = metadata
+	c.Credentials = auth.Chain{}
+	return c
+}
+
+func TestOfflineListReleasesStopsAtUncachedPage(t *testing.T) {
+	c := cachedClient(t, releasesServer(t))
+
+	// Online, only the first page is needed and cached
+	if _, err := c.ListReleases("owner/repo", func([]Release) bool { return false }); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Offline = true
+	releases, err := c.ListReleases("owner/repo", nil)
+	if err != nil {
+		t.Fatalf("ListReleases() error = %v", err)
+	}
+	if len(releases) != 1 || releases[0].TagName != "v2.0.0" {
+		t.Errorf("ListReleases() = %+v, want the cached first page", releases)
+	}
+}
+
+func TestOfflineListReleasesNeedsFirstPage(t *testing.T) {
+	c := cachedClient(t, releasesServer(t))
+	c.Offline = true
+	if _, err := c.ListReleases("owner/repo", nil); !errors.Is(err, ErrOffline) {
+		t.Errorf("ListReleases() error = %v, want %v", err, ErrOffline)
+	}
+}
+
+func TestOfflineReleaseByTagFromListedPages(t *testing.T) {
+	c := cachedClient(t, releasesServer(t))
+	if _, err := c.ListReleases("owner/repo", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Offline = true
+	digest, err := c.GetAssetChecksum("owner/repo", "v2.0.0", "tool.tar.gz")
+	if err != nil {
+		t.Fatalf("GetAssetChecksum() error = %v", err)
+	}
+	if digest != "abc" {
+		t.Errorf("GetAssetChecksum() = %q, want %q", digest, "abc")
+	}
+	if _, err := c.GetReleaseByTag("owner/repo", "v0.1.0"); !errors.Is(err, ErrOffline) {
+		t.Errorf("GetReleaseByTag() of an unlisted tag error = %v, want %v", err, ErrOffline)
+	}
+}
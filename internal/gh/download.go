@@ -0,0 +1,260 @@
+package gh
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is the default number of download retries
+	DefaultMaxRetries = 5
+	// DefaultIdleTimeout is the default time a download may go without receiving data
+	DefaultIdleTimeout = 30 * time.Second
+
+	baseBackoff = time.Second
+	maxBackoff  = 30 * time.Second
+	// maxRetryAfter bounds how long a server-provided Retry-After is honored
+	maxRetryAfter = 5 * time.Minute
+)
+
+// Progress receives download progress. total is -1 when unknown.
+type Progress interface {
+	DownloadStart(name string, total int64)
+	DownloadProgress(name string, done, total int64)
+	DownloadDone(name string, size int64)
+}
+
+// retryableError marks a failed attempt that may succeed if retried
+type retryableError struct {
+	err        error
+	retryAfter time.Duration // Server-requested delay, if any
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// AssetURL returns the browser download URL of a release asset
+func (c *Client) AssetURL(repo, tag, filename string) string {
+	return fmt.Sprintf("%s/%s/releases/download/%s/%s", c.webBaseURL(), repo, tag, filename)
+}
+
+// DownloadReleaseAsset downloads a release asset to a destination path
+func (c *Client) DownloadReleaseAsset(repo, tag, filename, destPath string) error {
+	return c.Download(c.AssetURL(repo, tag, filename), filename, destPath, nil)
+}
+
+// Download fetches url to destPath, sending header with each attempt.
+// Data is written to destPath+".part" first; an existing .part file is resumed
+// with an HTTP Range request. Server errors, 429s and connection failures are
+// retried with exponential backoff and jitter, honoring Retry-After.
+func (c *Client) Download(url, filename, destPath string, header http.Header) error {
+	partPath := destPath + ".part"
+
+	maxRetries := c.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = DefaultMaxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := c.downloadAttempt(url, filename, partPath, header)
+		if err == nil {
+			if c.Progress != nil {
+				if info, err := os.Stat(partPath); err == nil {
+					c.Progress.DownloadDone(filename, info.Size())
+				}
+			}
+			return os.Rename(partPath, destPath)
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || attempt >= maxRetries {
+			return err
+		}
+
+		delay := backoff(attempt)
+		if retryable.retryAfter > 0 {
+			delay = min(retryable.retryAfter, maxRetryAfter)
+		}
+		fmt.Fprintf(os.Stderr, "Download of %s failed (%v), retrying in %s...\n", filename, err, delay.Round(time.Millisecond))
+		time.Sleep(delay)
+	}
+}
+
+// downloadAttempt fetches url into partPath, resuming from its current size
+func (c *Client) downloadAttempt(url, filename, partPath string, header http.Header) error {
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	idleTimeout := c.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	idle := time.AfterFunc(idleTimeout, cancel)
+	defer idle.Stop()
+
+	req, err := c.newRequest("GET", url, header)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/octet-stream")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := c.downloadClient.Do(req)
+	if err != nil {
+		// An untrusted certificate won't become trusted by retrying
+		var certErr *tls.CertificateVerificationError
+		if errors.As(err, &certErr) {
+			return err
+		}
+		return &retryableError{err: err}
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	total := resp.ContentLength
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && contentRangeStart(resp.Header.Get("Content-Range")) == offset:
+		flags |= os.O_APPEND
+		total = contentRangeTotal(resp.Header.Get("Content-Range"))
+	case resp.StatusCode == http.StatusOK:
+		// Server ignored the range (or there was none): start over
+		flags |= os.O_TRUNC
+		offset = 0
+	case resp.StatusCode == http.StatusPartialContent:
+		// Unexpected range: discard the partial file and retry from scratch
+		os.Remove(partPath)
+		return &retryableError{err: fmt.Errorf("download failed: unexpected Content-Range %q", resp.Header.Get("Content-Range"))}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is already complete, or stale
+		if total := contentRangeTotal(resp.Header.Get("Content-Range")); total == offset {
+			return nil
+		}
+		os.Remove(partPath)
+		return &retryableError{err: fmt.Errorf("download failed: %s", resp.Status)}
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return &retryableError{
+			err:        fmt.Errorf("download failed: %w", responseError(resp)),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	default:
+		return fmt.Errorf("download failed: %w", responseError(resp))
+	}
+
+	out, err := os.OpenFile(partPath, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	var body io.Reader = &idleReader{r: resp.Body, timer: idle, timeout: idleTimeout}
+	if c.Progress != nil {
+		c.Progress.DownloadStart(filename, total)
+		body = &progressReader{r: body, name: filename, done: offset, total: total, progress: c.Progress}
+	}
+	if _, err := io.Copy(out, body); err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("no data received for %s", idleTimeout)
+		}
+		return &retryableError{err: err}
+	}
+	return out.Close()
+}
+
+// idleReader resets an idle timer on every successful read
+type idleReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
+
+// progressReader reports cumulative bytes read
+type progressReader struct {
+	r        io.Reader
+	name     string
+	done     int64
+	total    int64
+	progress Progress
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.done += int64(n)
+		r.progress.DownloadProgress(r.name, r.done, r.total)
+	}
+	return n, err
+}
+
+// backoff returns the delay before retry attempt+1: exponential, jittered to 50-100%
+func backoff(attempt int) time.Duration {
+	d := min(baseBackoff<<attempt, maxBackoff)
+	return d/2 + rand.N(d/2+1)
+}
+
+// parseRetryAfter parses a Retry-After header in seconds or HTTP-date form
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// contentRangeStart returns the first byte of a "bytes start-end/total" header, or -1
+func contentRangeStart(v string) int64 {
+	rest, ok := strings.CutPrefix(v, "bytes ")
+	if !ok {
+		return -1
+	}
+	start, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// contentRangeTotal returns the total size from a Content-Range header, or -1
+func contentRangeTotal(v string) int64 {
+	_, total, ok := strings.Cut(v, "/")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,199 @@
+package gh
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rewriteTransport sends every request to target, keeping the path and the
+// original Host header
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Host = req.URL.Host
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// assetServer records the Range header of each download request
+type assetServer struct {
+	mu     sync.Mutex
+	ranges []string
+}
+
+func (s *assetServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ranges...)
+}
+
+// downloadClient returns a client whose downloads are served by handler.
+// handler gets the 0-based number of the request.
+func downloadClient(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, n int)) (*Client, *assetServer) {
+	t.Helper()
+	s := &assetServer{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		n := len(s.ranges)
+		s.ranges = append(s.ranges, r.Header.Get("Range"))
+		s.mu.Unlock()
+		handler(w, r, n)
+	}))
+	t.Cleanup(srv.Close)
+
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient()
+	c.downloadClient = &http.Client{Transport: rewriteTransport{target: target}}
+	c.MaxRetries = 2
+	return c, s
+}
+
+// assetBody is the content of the served asset; half of it is sent before
+// an interruption
+var (
+	assetBody = bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	half      = len(assetBody) / 2
+)
+
+// serveRest answers a Range request from offset with 206
+func serveRest(t *testing.T, w http.ResponseWriter, r *http.Request, offset int) {
+	if got, want := r.Header.Get("Range"), fmt.Sprintf("bytes=%d-", offset); got != want {
+		t.Errorf("Range = %q, want %q", got, want)
+		http.Error(w, "bad range", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(assetBody)-1, len(assetBody)))
+	w.Header().Set("Content-Length", strconv.Itoa(len(assetBody)-offset))
+	w.WriteHeader(http.StatusPartialContent)
+	w.Write(assetBody[offset:])
+}
+
+// serveHalf sends the headers of the whole asset but only its first half
+func serveHalf(w http.ResponseWriter) {
+	w.Header().Set("Content-Length", strconv.Itoa(len(assetBody)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(assetBody[:half])
+	w.(http.Flusher).Flush()
+}
+
+func checkDownloaded(t *testing.T, dest string) {
+	t.Helper()
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, assetBody) {
+		t.Errorf("downloaded %d bytes, want the %d-byte asset", len(data), len(assetBody))
+	}
+	if _, err := os.Stat(dest + ".part"); err == nil {
+		t.Error("partial file left behind")
+	}
+}
+
+func TestDownloadResumesAfterDroppedConnection(t *testing.T) {
+	c, s := downloadClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		if n == 0 {
+			serveHalf(w)
+			// Drop the connection mid-body
+			panic(http.ErrAbortHandler)
+		}
+		serveRest(t, w, r, half)
+	})
+
+	dest := filepath.Join(t.TempDir(), "tool.tar.gz")
+	if err := c.DownloadReleaseAsset("owner/tool", "v1.0.0", "tool.tar.gz", dest); err != nil {
+		t.Fatalf("DownloadReleaseAsset() error = %v", err)
+	}
+	checkDownloaded(t, dest)
+	if got := s.requests(); len(got) != 2 {
+		t.Errorf("%d requests, want 2", len(got))
+	}
+}
+
+func TestDownloadRestartsWhenRangeIgnored(t *testing.T) {
+	c, s := downloadClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		w.Write(assetBody)
+	})
+
+	dest := filepath.Join(t.TempDir(), "tool.tar.gz")
+	if err := os.WriteFile(dest+".part", []byte("stale partial download"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DownloadReleaseAsset("owner/tool", "v1.0.0", "tool.tar.gz", dest); err != nil {
+		t.Fatalf("DownloadReleaseAsset() error = %v", err)
+	}
+	checkDownloaded(t, dest)
+	if got := s.requests(); len(got) != 1 || got[0] == "" {
+		t.Errorf("requests with Range %q, want one ranged request", got)
+	}
+}
+
+func TestDownloadHonorsRetryAfter(t *testing.T) {
+	c, s := downloadClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		if n == 0 {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		w.Write(assetBody)
+	})
+
+	dest := filepath.Join(t.TempDir(), "tool.tar.gz")
+	start := time.Now()
+	if err := c.DownloadReleaseAsset("owner/tool", "v1.0.0", "tool.tar.gz", dest); err != nil {
+		t.Fatalf("DownloadReleaseAsset() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %s, want the 1s Retry-After", elapsed)
+	}
+	checkDownloaded(t, dest)
+	if got := s.requests(); len(got) != 2 {
+		t.Errorf("%d requests, want 2", len(got))
+	}
+}
+
+func TestDownloadIdleTimeout(t *testing.T) {
+	const stall = 5 * time.Second
+	c, s := downloadClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		if n == 0 {
+			serveHalf(w)
+			// Stop sending without closing the connection
+			select {
+			case <-r.Context().Done():
+			case <-time.After(stall):
+			}
+			return
+		}
+		serveRest(t, w, r, half)
+	})
+	c.IdleTimeout = 100 * time.Millisecond
+
+	dest := filepath.Join(t.TempDir(), "tool.tar.gz")
+	start := time.Now()
+	if err := c.DownloadReleaseAsset("owner/tool", "v1.0.0", "tool.tar.gz", dest); err != nil {
+		t.Fatalf("DownloadReleaseAsset() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= stall {
+		t.Errorf("download took %s, want the stalled attempt abandoned after the idle timeout", elapsed)
+	}
+	checkDownloaded(t, dest)
+	if got := s.requests(); len(got) != 2 {
+		t.Errorf("%d requests, want 2", len(got))
+	}
+}
@@ -0,0 +1,72 @@
+package gh
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrNotFound is returned when the requested release, asset or resource does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrOffline is returned in offline mode for anything that needs the network
+var ErrOffline = errors.New("offline")
+
+// StatusError is returned when GitHub, or another forge fetched through the
+// client, answers with an unexpected HTTP status. A 404 matches ErrNotFound.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	URL        string
+}
+
+func (e *StatusError) Error() string {
+	host := "github"
+	if u, err := url.Parse(e.URL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	return fmt.Sprintf("%s returned status: %s", host, e.Status)
+}
+
+func (e *StatusError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// RateLimitError is returned when GitHub rejects a request because the rate
+// limit is exhausted
+type RateLimitError struct {
+	Limit int       // Requests allowed per window, 0 if unknown
+	Reset time.Time // When the limit resets, zero if unknown
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Reset.IsZero() {
+		return "github api rate limit exceeded"
+	}
+	return fmt.Sprintf("github api rate limit exceeded, resets at %s (in %s)",
+		e.Reset.Local().Format(time.TimeOnly), max(time.Until(e.Reset), 0).Round(time.Second))
+}
+
+// responseError converts a failed response into a *RateLimitError or *StatusError.
+// Secondary rate limits answer 403 with Retry-After while requests remain, so
+// Retry-After takes precedence over X-RateLimit-Reset.
+func responseError(resp *http.Response) error {
+	retryAfter := resp.Header.Get("Retry-After")
+	limited := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && (resp.Header.Get("X-RateLimit-Remaining") == "0" || retryAfter != ""))
+	if !limited {
+		return &StatusError{StatusCode: resp.StatusCode, Status: resp.Status, URL: resp.Request.URL.Redacted()}
+	}
+
+	e := &RateLimitError{}
+	e.Limit, _ = strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if d := parseRetryAfter(retryAfter); d > 0 {
+		e.Reset = time.Now().Add(d)
+	} else if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		e.Reset = time.Unix(reset, 0)
+	}
+	return e
+}
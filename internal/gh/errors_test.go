@@ -0,0 +1,61 @@
+package gh
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestResponseError(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Unix()
+	tests := []struct {
+		name    string
+		status  int
+		header  map[string]string
+		limited bool
+		wait    time.Duration // Expected time until reset, 0 to skip the check
+	}{
+		{"not found", http.StatusNotFound, nil, false, 0},
+		{"forbidden", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "10"}, false, 0},
+		{"primary limit", http.StatusForbidden, map[string]string{
+			"X-RateLimit-Remaining": "0",
+			"X-RateLimit-Reset":     strconv.FormatInt(reset, 10),
+		}, true, time.Hour},
+		{"too many requests", http.StatusTooManyRequests, map[string]string{"Retry-After": "30"}, true, 30 * time.Second},
+		// Secondary limits keep requests remaining and name the wait in Retry-After
+		{"secondary limit", http.StatusForbidden, map[string]string{
+			"Retry-After":           "60",
+			"X-RateLimit-Remaining": "4000",
+			"X-RateLimit-Reset":     strconv.FormatInt(reset, 10),
+		}, true, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tt.status,
+				Status:     http.StatusText(tt.status),
+				Header:     http.Header{},
+				Request:    &http.Request{URL: &url.URL{Scheme: "https", Host: "api.github.com", Path: "/repos/o/r"}},
+			}
+			for k, v := range tt.header {
+				resp.Header.Set(k, v)
+			}
+
+			err := responseError(resp)
+			var rateLimit *RateLimitError
+			if got := errors.As(err, &rateLimit); got != tt.limited {
+				t.Fatalf("responseError() = %v, rate limited %v, want %v", err, got, tt.limited)
+			}
+			if tt.wait == 0 {
+				return
+			}
+			if got := time.Until(rateLimit.Reset); got < tt.wait-5*time.Second || got > tt.wait+time.Second {
+				t.Errorf("Reset in %s, want %s", got, tt.wait)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package gh
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Mirror rewrites URLs that start with From to start with To instead. A
+// trailing "*" on either side marks the rest of the URL and is optional, so
+// "https://github.com/* -> https://mirror.corp/github/*" and
+// "https://github.com/ -> https://mirror.corp/github/" are the same rule.
+// From only matches up to a path boundary, so "https://github.com" covers
+// https://github.com/... but not https://github.com.evil.example/...
+type Mirror struct {
+	From string
+	To   string
+}
+
+// ParseMirror validates a rewrite rule
+func ParseMirror(from, to string) (Mirror, error) {
+	m := Mirror{From: strings.TrimSuffix(from, "*"), To: strings.TrimSuffix(to, "*")}
+	if m.From == "" {
+		return Mirror{}, errors.New("mirror: empty source pattern")
+	}
+	u, err := url.Parse(m.To)
+	if err != nil {
+		return Mirror{}, fmt.Errorf("mirror %q: %w", to, err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return Mirror{}, fmt.Errorf("mirror %q is not an http(s) URL", to)
+	}
+	return m, nil
+}
+
+// ParseMirrors parses comma-separated "from -> to" rules, as in
+// SOUS_CHEF_MIRRORS
+func ParseMirrors(s string) ([]Mirror, error) {
+	var mirrors []Mirror
+	for rule := range strings.SplitSeq(s, ",") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(rule, "->")
+		if !ok {
+			return nil, fmt.Errorf("mirror %q: expected \"from -> to\"", strings.TrimSpace(rule))
+		}
+		m, err := ParseMirror(strings.TrimSpace(from), strings.TrimSpace(to))
+		if err != nil {
+			return nil, err
+		}
+		mirrors = append(mirrors, m)
+	}
+	return mirrors, nil
+}
+
+// match returns the rest of rawURL after From, if From is a prefix ending at
+// a path boundary
+func (m Mirror) match(rawURL string) (string, bool) {
+	rest, ok := strings.CutPrefix(rawURL, m.From)
+	if !ok {
+		return "", false
+	}
+	if rest == "" || strings.HasSuffix(m.From, "/") || strings.ContainsRune("/?#", rune(rest[0])) {
+		return rest, true
+	}
+	return "", false
+}
+
+// rewriteURL applies the first matching mirror rule to rawURL
+func (c *Client) rewriteURL(rawURL string) string {
+	for _, m := range c.Mirrors {
+		if rest, ok := m.match(rawURL); ok {
+			return m.To + rest
+		}
+	}
+	return rawURL
+}
+
+// Mirrored reports whether a mirror moves requests for rawURL to another
+// host, where credentials for the original host must not be sent
+func (c *Client) Mirrored(rawURL string) bool {
+	return !sameHost(rawURL, c.rewriteURL(rawURL))
+}
+
+// LocalAsset returns the path of a release asset in LocalMirror, if it is there
+func (c *Client) LocalAsset(repo, tag, filename string) (string, bool) {
+	if c.LocalMirror == "" {
+		return "", false
+	}
+	rel := filepath.Join(filepath.FromSlash(repo), tag, filename)
+	if !filepath.IsLocal(rel) {
+		return "", false
+	}
+	path := filepath.Join(c.LocalMirror, rel)
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	return path, true
+}
+
+// LoadCABundle trusts the PEM certificates in path in addition to the system
+// roots, for mirrors and proxies that terminate TLS with an internal CA
+func (c *Client) LoadCABundle(path string) error {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+
+	for _, hc := range []*http.Client{c.httpClient, c.downloadClient} {
+		t, ok := hc.Transport.(*http.Transport)
+		if !ok {
+			continue
+		}
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+	return nil
+}
+
+// sameHost reports whether two URLs share a scheme and host, i.e. whether
+// credentials meant for one may be sent to the other
+func sameHost(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	return errA == nil && errB == nil && ua.Scheme == ub.Scheme && ua.Host == ub.Host
+}
@@ -0,0 +1,84 @@
+package gh
+
+import (
+	"testing"
+
+	"github.com/aniaan/sous-chef/internal/auth"
+)
+
+// staticToken is a credential provider with the same token for every host
+type staticToken string
+
+func (staticToken) Name() string { return "test" }
+
+func (t staticToken) Token(string) (string, string, error) { return string(t), "test", nil }
+
+func mirrorClient(t *testing.T, rules string) *Client {
+	t.Helper()
+	mirrors, err := ParseMirrors(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient()
+	c.Mirrors = mirrors
+	c.Credentials = auth.Chain{staticToken("secret")}
+	return c
+}
+
+func TestRewriteURL(t *testing.T) {
+	c := mirrorClient(t, "https://github.com/owner/* -> https://owner-mirror.corp/*, "+
+		"https://github.com/ -> https://mirror.corp/github/, "+
+		"https://api.github.com/* -> https://mirror.corp/api/*, "+
+		"https://codeberg.org -> https://mirror.corp/codeberg")
+
+	tests := []struct {
+		url, want string
+	}{
+		// First matching rule wins
+		{"https://github.com/owner/tool/releases/download/v1/tool.tar.gz", "https://owner-mirror.corp/tool/releases/download/v1/tool.tar.gz"},
+		{"https://github.com/other/tool/releases/download/v1/tool.tar.gz", "https://mirror.corp/github/other/tool/releases/download/v1/tool.tar.gz"},
+		{"https://api.github.com/repos/owner/tool/releases", "https://mirror.corp/api/repos/owner/tool/releases"},
+		{"https://gitlab.com/owner/tool", "https://gitlab.com/owner/tool"},
+		// Rules without a trailing slash match at a path boundary only
+		{"https://codeberg.org/owner/tool", "https://mirror.corp/codeberg/owner/tool"},
+		{"https://codeberg.org?page=1", "https://mirror.corp/codeberg?page=1"},
+		{"https://codeberg.org", "https://mirror.corp/codeberg"},
+		{"https://codeberg.org.evil.example/owner/tool", "https://codeberg.org.evil.example/owner/tool"},
+		{"https://codeberg.organic.example/owner/tool", "https://codeberg.organic.example/owner/tool"},
+	}
+	for _, tt := range tests {
+		if got := c.rewriteURL(tt.url); got != tt.want {
+			t.Errorf("rewriteURL(%s) = %s, want %s", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestMirrorTokenGuard(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     string
+		wantToken bool
+	}{
+		{"no mirror", "", true},
+		{"mirror on another host", "https://api.github.com/* -> https://mirror.corp/api/*", false},
+		{"mirror on the same host", "https://api.github.com/repos/* -> https://api.github.com/proxy/repos/*", true},
+		{"mirror downgrading to http", "https://api.github.com/* -> http://api.github.com/*", false},
+		{"mirror of the host without a slash", "https://api.github.com -> https://mirror.corp/api", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const url = "https://api.github.com/repos/owner/tool/releases"
+			c := mirrorClient(t, tt.rules)
+			if got := c.Mirrored(url); got == tt.wantToken {
+				t.Errorf("Mirrored() = %v, want %v", got, !tt.wantToken)
+			}
+			req, err := c.newRequest("GET", url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := req.Header.Get("Authorization") != ""; got != tt.wantToken {
+				t.Errorf("token sent = %v, want %v (request to %s)", got, tt.wantToken, req.URL)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package gh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// RateLimitReserve is the number of API requests left at which optional
+	// lookups are skipped
+	RateLimitReserve = 5
+
+	// maxRateLimitWaits bounds how often one request waits for a reset
+	maxRateLimitWaits = 3
+)
+
+// ErrRateLimitReserved is returned by optional lookups skipped to keep the
+// last RateLimitReserve requests for the ones that matter
+var ErrRateLimitReserved = errors.New("skipped to preserve the GitHub API rate limit")
+
+// RateLimit is a rate limit window as reported by the X-RateLimit-* headers
+type RateLimit struct {
+	Resource  string    `json:"resource"`
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Used      int       `json:"used"`
+	Reset     time.Time `json:"reset"`
+}
+
+func (l RateLimit) String() string {
+	return fmt.Sprintf("%d/%d requests left, resets at %s (in %s)",
+		l.Remaining, l.Limit, l.Reset.Local().Format(time.TimeOnly), max(time.Until(l.Reset), 0).Round(time.Second))
+}
+
+// Low reports whether less than a tenth of the window is left
+func (l RateLimit) Low() bool {
+	return l.Remaining <= max(l.Limit/10, RateLimitReserve)
+}
+
+// RateLimit returns the most recently observed rate limit, and false if no
+// API response has been seen yet
+func (c *Client) RateLimit() (RateLimit, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rateLimit, !c.rateLimit.Reset.IsZero()
+}
+
+// RateLimitNearlyExhausted reports whether at most RateLimitReserve requests
+// are left in the current window
+func (c *Client) RateLimitNearlyExhausted() bool {
+	l, ok := c.RateLimit()
+	return ok && l.Remaining <= RateLimitReserve && time.Now().Before(l.Reset)
+}
+
+// observeRateLimit records the rate limit headers of an API response and
+// warns once when the budget runs low
+func (c *Client) observeRateLimit(resp *http.Response) {
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	l := RateLimit{Resource: resp.Header.Get("X-RateLimit-Resource"), Reset: time.Unix(reset, 0)}
+	l.Limit, _ = strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	l.Remaining, _ = strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	l.Used, _ = strconv.Atoi(resp.Header.Get("X-RateLimit-Used"))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rateLimit = l
+	if l.Low() && !c.rateLimitWarned {
+		c.rateLimitWarned = true
+		fmt.Fprintf(os.Stderr, "Warning: GitHub API rate limit is low: %s\n", l)
+	}
+}
+
+// waitForReset sleeps until a rate limit resets if the client is configured
+// to wait, reporting whether the request should be retried
+func (c *Client) waitForReset(err error, attempt int) bool {
+	var rateLimit *RateLimitError
+	if !c.WaitOnRateLimit || attempt >= maxRateLimitWaits || !errors.As(err, &rateLimit) || rateLimit.Reset.IsZero() {
+		return false
+	}
+	// A second of slack for clock skew
+	wait := max(time.Until(rateLimit.Reset), 0) + time.Second
+	fmt.Fprintf(os.Stderr, "GitHub API rate limit exceeded, waiting %s until it resets...\n", wait.Round(time.Second))
+	time.Sleep(wait)
+	return true
+}
+
+// GetRateLimits fetches the current quota of every rate limit resource.
+// Querying it does not count against the limit.
+func (c *Client) GetRateLimits() ([]RateLimit, error) {
+	req, err := c.newAPIRequest("GET", c.apiBaseURL()+"/rate_limit")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	var body struct {
+		Resources map[string]struct {
+			Limit     int   `json:"limit"`
+			Remaining int   `json:"remaining"`
+			Used      int   `json:"used"`
+			Reset     int64 `json:"reset"`
+		} `json:"resources"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	limits := make([]RateLimit, 0, len(body.Resources))
+	for name, r := range body.Resources {
+		limits = append(limits, RateLimit{
+			Resource:  name,
+			Limit:     r.Limit,
+			Remaining: r.Remaining,
+			Used:      r.Used,
+			Reset:     time.Unix(r.Reset, 0),
+		})
+	}
+	return limits, nil
+}
@@ -0,0 +1,101 @@
+package installer
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aniaan/sous-chef/internal/progress"
+	"github.com/aniaan/sous-chef/internal/registry"
+	"github.com/aniaan/sous-chef/internal/source"
+)
+
+// resolveChecksum returns the expected SHA-256 of filename. The digest reported
+// by the forge is preferred; otherwise the plugin's checksum asset is downloaded
+// into tempDir and parsed. An empty result means no checksum is published.
+// release may be nil when fetching it failed with releaseErr.
+func resolveChecksum(src source.Source, plugin *registry.PluginConfig, ctx Context, release *source.Release, releaseErr error, tag, filename, tempDir string, r progress.Reporter) (string, error) {
+	var checksum string
+	if release != nil {
+		checksum = release.AssetDigest(filename)
+	}
+	if checksum != "" || plugin.Checksum == nil {
+		return checksum, releaseErr
+	}
+
+	name, err := renderTemplate(plugin.Checksum.AssetTemplate, assetContext{Context: ctx, Asset: filename})
+	if err != nil {
+		return "", fmt.Errorf("failed to render checksum asset name: %w", err)
+	}
+
+	r.Logf("Fetching checksums from %s...", name)
+	// url sources may name the checksum file by its full URL
+	dest := filepath.Join(tempDir, path.Base(name))
+	if err := src.DownloadAsset(plugin.Repo, tag, name, dest); err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", name, err)
+	}
+
+	return parseChecksumFile(dest, plugin.Checksum.Format, filename)
+}
+
+// parseChecksumFile finds the SHA-256 for filename in a checksum file
+func parseChecksumFile(file string, format registry.ChecksumFormat, filename string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var hash, name string
+		switch format {
+		case registry.ChecksumSingle:
+			// The first field is the hash; anything after it is informational
+			hash = strings.Fields(line)[0]
+			name = filename
+		case registry.ChecksumBSD:
+			// SHA256 (name) = hash
+			rest, ok := strings.CutPrefix(line, "SHA256 (")
+			if !ok {
+				continue
+			}
+			i := strings.LastIndex(rest, ") = ")
+			if i < 0 {
+				continue
+			}
+			name, hash = rest[:i], rest[i+len(") = "):]
+		default:
+			// hash  name, or hash *name in binary mode
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			hash = fields[0]
+			name = strings.TrimPrefix(strings.Join(fields[1:], " "), "*")
+		}
+
+		if path.Base(name) != filename {
+			continue
+		}
+		hash = strings.ToLower(hash)
+		if b, err := hex.DecodeString(hash); err != nil || len(b) != 32 {
+			return "", fmt.Errorf("invalid sha256 for %s in %s", filename, filepath.Base(file))
+		}
+		return hash, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("no checksum for %s in %s", filename, filepath.Base(file))
+}
@@ -0,0 +1,54 @@
+package installer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aniaan/sous-chef/internal/registry"
+)
+
+func TestParseChecksumFile(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	other := strings.Repeat("cd", 32)
+	tests := []struct {
+		name    string
+		format  registry.ChecksumFormat
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"gnu", registry.ChecksumGNU, other + "  tool-linux.tar.gz\n" + hash + "  tool.tar.gz\n", hash, false},
+		{"gnu binary mode", registry.ChecksumGNU, hash + " *tool.tar.gz\n", hash, false},
+		{"gnu path", registry.ChecksumGNU, hash + "  dist/tool.tar.gz\n", hash, false},
+		{"gnu uppercase", registry.ChecksumGNU, strings.ToUpper(hash) + "  tool.tar.gz\n", hash, false},
+		{"gnu comments", registry.ChecksumGNU, "# sha256\n\n" + hash + "  tool.tar.gz\n", hash, false},
+		{"gnu missing", registry.ChecksumGNU, other + "  tool-linux.tar.gz\n", "", true},
+		{"gnu prefix is not a match", registry.ChecksumGNU, hash + "  tool.tar.gz.sig\n", "", true},
+		{"bsd", registry.ChecksumBSD, "SHA256 (other.zip) = " + other + "\nSHA256 (tool.tar.gz) = " + hash + "\n", hash, false},
+		{"bsd missing", registry.ChecksumBSD, "SHA256 (tool (1).tar.gz) = " + hash + "\n", "", true},
+		{"bsd other algorithm", registry.ChecksumBSD, "SHA512 (tool.tar.gz) = " + hash + "\n", "", true},
+		{"single", registry.ChecksumSingle, hash + "\n", hash, false},
+		{"single with name", registry.ChecksumSingle, hash + "  tool.tar.gz\n", hash, false},
+		{"single empty", registry.ChecksumSingle, "\n", "", true},
+		{"short hash", registry.ChecksumGNU, "abcd  tool.tar.gz\n", "", true},
+		{"not hex", registry.ChecksumSingle, strings.Repeat("zz", 32) + "\n", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "checksums.txt")
+			if err := os.WriteFile(file, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseChecksumFile(file, tt.format, "tool.tar.gz")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseChecksumFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseChecksumFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,18 +1,23 @@
 package installer
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 
+	"github.com/aniaan/sous-chef/internal/cache"
 	"github.com/aniaan/sous-chef/internal/gh"
+	"github.com/aniaan/sous-chef/internal/lock"
+	"github.com/aniaan/sous-chef/internal/progress"
 	"github.com/aniaan/sous-chef/internal/registry"
+	"github.com/aniaan/sous-chef/internal/source"
 	"github.com/aniaan/sous-chef/internal/util"
+	"github.com/aniaan/sous-chef/internal/verify"
 )
 
 type Context struct {
@@ -21,11 +26,84 @@ type Context struct {
 	Arch     string
 }
 
+// assetContext is the template data for assets published alongside the main
+// one, such as checksum and signature files
+type assetContext struct {
+	Context
+	Asset string
+}
+
+// Options configures an installation
+type Options struct {
+	Client    *gh.Client
+	Downloads *cache.Downloads // Optional download cache
+
+	// RequireChecksum refuses to install assets that cannot be verified.
+	// Offline clients always require a checksum.
+	RequireChecksum bool
+	// RequireSignature refuses to install when a tool's signature is not published
+	RequireSignature bool
+
+	// Progress receives status messages and lifecycle events (default: plain text)
+	Progress progress.Reporter
+
+	// LockTimeout bounds how long to wait for another install holding the
+	// install directory or a cache entry (0 = lock.DefaultTimeout)
+	LockTimeout time.Duration
+
+	// ShareDir, if set, receives symlinks to the installed completions and
+	// man pages, so one MANPATH and FPATH entry covers every tool
+	ShareDir string
+}
+
+var (
+	// ErrChecksumMismatch is returned when an asset does not match its published SHA-256
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrChecksumMissing is returned under RequireChecksum when no SHA-256 is published
+	ErrChecksumMissing = errors.New("no checksum published")
+	// ErrBinaryNotFound is returned when the extracted asset lacks the tool's binary
+	ErrBinaryNotFound = errors.New("binary not found")
+)
+
+// ChecksumStatus records how an installed asset was verified
+type ChecksumStatus string
+
+const (
+	ChecksumVerified ChecksumStatus = "verified" // Matched the published SHA-256
+	ChecksumMissing  ChecksumStatus = "missing"  // Nothing published, installed unverified
+)
+
+// Result describes a completed installation
+type Result struct {
+	Tag            string
+	Release        *source.Release // nil if the release metadata could not be fetched
+	Asset          string
+	DownloadURL    string
+	Checksum       string // Expected SHA-256, empty when ChecksumStatus is ChecksumMissing
+	ChecksumStatus ChecksumStatus
+	InstallPath    string
+}
+
+func (o Options) reporter() progress.Reporter {
+	if o.Progress != nil {
+		return o.Progress
+	}
+	return progress.New(progress.None, os.Stderr)
+}
+
+func (o Options) lockTimeout() time.Duration {
+	if o.LockTimeout > 0 {
+		return o.LockTimeout
+	}
+	return lock.DefaultTimeout
+}
+
 // Install handles the download and installation of a tool
-func Install(plugin *registry.PluginConfig, version, installDir string) error {
+func Install(plugin *registry.PluginConfig, version, installDir string, opts Options) (*Result, error) {
+	r := opts.reporter()
 	plat, arch, err := util.GetSystemInfo()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Map platform/arch to plugin specific strings
@@ -43,11 +121,16 @@ func Install(plugin *registry.PluginConfig, version, installDir string) error {
 		Platform: platStr,
 		Arch:     archStr,
 	}
+	cfg := plugin.Source
+	cfg.AssetData = func(_, asset string) any {
+		return assetContext{Context: ctx, Asset: asset}
+	}
+	src := source.Open(opts.Client, cfg)
 
 	// Render filename
 	filename, err := renderTemplate(plugin.AssetTemplate, ctx)
 	if err != nil {
-		return fmt.Errorf("failed to render filename: %w", err)
+		return nil, fmt.Errorf("failed to render filename: %w", err)
 	}
 
 	// Determine GitHub Tag
@@ -56,100 +139,595 @@ func Install(plugin *registry.PluginConfig, version, installDir string) error {
 		tag = plugin.RecoverVersion(version)
 	}
 
-	// Download to temp file
+	// Serialize installs into the same directory
+	installLock, err := lock.Acquire(installLockPath(installDir), opts.lockTimeout())
+	if err != nil {
+		return nil, err
+	}
+	defer installLock.Release()
+
 	tempDir, err := os.MkdirTemp("", "sous-chef")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer os.RemoveAll(tempDir) // Clean up
 
-	downloadPath := filepath.Join(tempDir, filename)
-	fmt.Printf("Downloading %s/%s@%s...\n", plugin.Repo, filename, tag)
+	// The release carries the API digest; it is optional when a checksum asset is declared
+	var release *source.Release
+	var releaseErr error
+	if plugin.Checksum != nil {
+		release, releaseErr = src.OptionalRelease(plugin.Repo, tag)
+		if errors.Is(releaseErr, gh.ErrRateLimitReserved) {
+			r.Logf("GitHub API rate limit nearly exhausted, using the checksum asset instead of the API digest")
+		}
+	} else {
+		release, releaseErr = src.GetRelease(plugin.Repo, tag)
+	}
 
-	client := gh.NewClient()
-	if err := client.DownloadReleaseAsset(plugin.Repo, tag, filename, downloadPath); err != nil {
-		return fmt.Errorf("failed to download asset: %w", err)
+	// Resolve the expected checksum up front so a cached asset can be checked against it
+	checksum, err := resolveChecksum(src, plugin, ctx, release, releaseErr, tag, filename, tempDir, r)
+	if checksum == "" && opts.Client.Offline && opts.Downloads != nil {
+		// Checksum files are not cached, but the digest of a cached asset was
+		// verified against one when it was stored
+		if digest, ok := opts.Downloads.Digest(plugin.Repo, tag, filename); ok {
+			checksum, err = digest, nil
+		}
+	}
+	requireChecksum := opts.RequireChecksum || opts.Client.Offline
+	if err != nil {
+		if requireChecksum {
+			return nil, fmt.Errorf("failed to get checksum: %w", err)
+		}
+		r.Logf("Warning: failed to get checksum: %v", err)
+	}
+	if checksum == "" && requireChecksum {
+		return nil, fmt.Errorf("%w for %s, refusing to install without verification", ErrChecksumMissing, filename)
 	}
 
-	// Verify Checksum
-	checksum, err := client.GetAssetChecksum(plugin.Repo, tag, filename)
+	downloadPath, err := fetchAsset(src, plugin.Repo, tag, filename, checksum, tempDir, opts)
 	if err != nil {
-		fmt.Printf("Warning: failed to get checksum: %v\n", err)
+		return nil, err
 	}
+	result := &Result{
+		Tag:            tag,
+		Release:        release,
+		Asset:          filename,
+		Checksum:       checksum,
+		ChecksumStatus: ChecksumVerified,
+		InstallPath:    installDir,
+	}
+	if checksum == "" {
+		result.ChecksumStatus = ChecksumMissing
+	}
+	// Only informational, and answered by the metadata cache for forges that need a lookup
+	result.DownloadURL, _ = src.AssetURL(plugin.Repo, tag, filename)
 
-	if checksum != "" {
-		fmt.Printf("Verifying checksum for %s...\n", filename)
-		if err := verifyChecksum(downloadPath, checksum); err != nil {
-			return fmt.Errorf("checksum verification failed: %w", err)
+	// Verify the signature before any extractor touches the archive
+	if plugin.Signature != nil {
+		r.Step(progress.EventVerify, fmt.Sprintf("Verifying %s signature for %s...", plugin.Signature.Type, filename))
+		err := verifySignature(src, plugin, ctx, tag, filename, downloadPath, tempDir)
+		switch {
+		case err == nil:
+			r.Step(progress.EventVerify, "Signature verified.")
+		case errors.Is(err, verify.ErrSignatureMissing) && !opts.RequireSignature:
+			r.Logf("Warning: %v, skipping signature verification.", err)
+		default:
+			return nil, err
 		}
-		fmt.Println("Checksum verified.")
-	} else {
-		fmt.Println("No checksum found in GitHub API, skipping verification.")
 	}
 
-	// Resolve relative binary path early
+	// Single compressed files and raw binaries are written to the binary path
 	relBinPath, err := renderTemplate(plugin.RelativeBinPathTemplate, ctx)
 	if err != nil {
-		return fmt.Errorf("failed to render relative bin path: %w", err)
+		return nil, fmt.Errorf("failed to render relative bin path: %w", err)
+	}
+
+	// Extract into a sibling staging directory; installDir is only replaced
+	// once every step below has succeeded
+	stageDir, err := newStagingDir(installDir)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stageDir) // No-op once committed
+
+	r.Step(progress.EventExtract, fmt.Sprintf("Extracting to %s...", stageDir))
+	// Without an archive_format, magic bytes win over the name so a misnamed
+	// archive isn't installed as the binary
+	extractOpts := util.ExtractOptions{
+		StripComponents: plugin.StripComponents,
+		Include:         plugin.Include,
+		Exclude:         plugin.Exclude,
+		Target:          relBinPath, // Single compressed files and raw binaries
+	}
+	if err := util.Extract(downloadPath, filename, stageDir, plugin.ArchiveFormat, extractOpts); err != nil {
+		return nil, err
+	}
+
+	// Check every bin entry before moving any file into bin/
+	bins, err := findBins(plugin.Bins, ctx, stageDir)
+	if err != nil {
+		return nil, err
 	}
+	if err := placeBins(stageDir, bins, r); err != nil {
+		return nil, err
+	}
+
+	if err := arrangeShareFiles(plugin, ctx, stageDir, r); err != nil {
+		return nil, err
+	}
+
+	if err := commitStaging(stageDir, installDir, r); err != nil {
+		return nil, err
+	}
+	if opts.ShareDir != "" {
+		// The tool works without them, so a failure here doesn't fail the install
+		if err := linkShareFiles(installDir, opts.ShareDir); err != nil {
+			r.Logf("Warning: failed to link completions and man pages into %s: %v", opts.ShareDir, err)
+		}
+	}
+	return result, nil
+}
+
+// fetchAsset places the asset in tempDir, from the download cache when a copy
+// matching checksum exists, otherwise by downloading and verifying it. The
+// cache entry is locked throughout so concurrent installs download it once.
+func fetchAsset(src source.Source, repo, tag, filename, checksum, tempDir string, opts Options) (string, error) {
+	r := opts.reporter()
+	downloadPath := filepath.Join(tempDir, filename)
+
+	if opts.Downloads != nil {
+		entryLock, err := opts.Downloads.Lock(repo, tag, filename, opts.lockTimeout())
+		if err != nil {
+			return "", err
+		}
+		defer entryLock.Release()
+
+		if blob, ok := lookupCachedAsset(opts.Downloads, repo, tag, filename, checksum); ok {
+			// Link rather than use the blob in place, so eviction by another
+			// process can't remove it mid-install
+			if err := os.Link(blob, downloadPath); err != nil {
+				if err := util.CopyFile(blob, downloadPath); err != nil {
+					return "", err
+				}
+			}
+			r.Logf("Using cached %s/%s@%s", repo, filename, tag)
+			return downloadPath, nil
+		}
+	}
+
+	r.Logf("Downloading %s/%s@%s...", repo, filename, tag)
+	if opts.Downloads == nil {
+		if err := src.DownloadAsset(repo, tag, filename, downloadPath); err != nil {
+			return "", downloadError(err)
+		}
+	} else {
+		// Download inside the cache so an interrupted download resumes next time
+		partial, err := opts.Downloads.PartialPath(repo, tag, filename)
+		if err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(filepath.Dir(partial), 0o755); err != nil {
+			return "", err
+		}
+		if err := src.DownloadAsset(repo, tag, filename, partial); err != nil {
+			return "", downloadError(err)
+		}
+		if err := moveFile(partial, downloadPath); err != nil {
+			return "", err
+		}
+	}
+
+	if checksum == "" {
+		r.Step(progress.EventVerify, "No checksum found, skipping verification.")
+		return downloadPath, nil
+	}
+
+	r.Step(progress.EventVerify, fmt.Sprintf("Verifying checksum for %s...", filename))
+	if err := verifyChecksum(downloadPath, checksum); err != nil {
+		return "", fmt.Errorf("checksum verification failed: %w", err)
+	}
+	r.Step(progress.EventVerify, "Checksum verified.")
+
+	// Only verified assets are cached
+	if opts.Downloads != nil {
+		if err := opts.Downloads.Store(repo, tag, filename, downloadPath, checksum); err != nil {
+			r.Logf("Warning: failed to cache download: %v", err)
+		}
+	}
+	return downloadPath, nil
+}
+
+// downloadError explains a failed asset download; offline, that means the
+// asset is in neither the download cache nor the local mirror
+func downloadError(err error) error {
+	if errors.Is(err, gh.ErrOffline) {
+		return fmt.Errorf("asset is not in the download cache or local mirror: %w", err)
+	}
+	return fmt.Errorf("failed to download asset: %w", err)
+}
+
+// lookupCachedAsset returns the cached copy of an asset if its digest matches
+// the expected checksum. Without an expected checksum the cache is not used.
+func lookupCachedAsset(downloads *cache.Downloads, repo, tag, filename, checksum string) (string, bool) {
+	if downloads == nil || checksum == "" {
+		return "", false
+	}
+	path, digest, ok := downloads.Lookup(repo, tag, filename)
+	if !ok || digest != checksum {
+		return "", false
+	}
+	return path, true
+}
+
+// moveFile renames src to dst, copying across filesystems if needed
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := util.CopyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// binFile is an extracted file exposed in bin/
+type binFile struct {
+	path string // Relative to the staging directory
+	name string // File name in bin/
+	link bool   // Shipped as a symlink
+}
 
-	// Extract
-	fmt.Printf("Extracting to %s...\n", installDir)
-	if strings.HasSuffix(filename, ".tar.gz") {
-		if err := util.ExtractTarGz(downloadPath, installDir, plugin.StripComponents); err != nil {
+// findBins matches the bin entries against the extracted files. Each entry
+// must match at least one file, a named entry exactly one, and no two files
+// may take the same name in bin/ or be overwritten by another's move.
+// Symlinks come last, so the files they point to have been moved first.
+func findBins(entries []registry.BinConfig, ctx Context, stageDir string) ([]binFile, error) {
+	// Globbing an fs.FS keeps the staging directory's own name out of patterns
+	stage := os.DirFS(stageDir)
+	var bins []binFile
+	names := make(map[string]string) // name in bin/ -> path
+	for _, entry := range entries {
+		pattern, files, err := globFiles(stage, entry.PathTemplate, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("bin path: %w", err)
+		}
+		if len(files) == 0 {
+			if entry.Name != "" {
+				return nil, fmt.Errorf("%w: %s at %s", ErrBinaryNotFound, entry.Name, pattern)
+			}
+			return nil, fmt.Errorf("%w at %s", ErrBinaryNotFound, pattern)
+		}
+		if entry.Name != "" && len(files) > 1 {
+			return nil, fmt.Errorf("bin path %q matches %d files, but a name is only valid for one", pattern, len(files))
+		}
+
+		for _, file := range files {
+			name := entry.Name
+			if name == "" {
+				name = filepath.Base(file)
+			}
+			if prev, ok := names[name]; ok {
+				if prev == file {
+					continue // Matched by an earlier entry
+				}
+				return nil, fmt.Errorf("bin/%s would be installed from both %s and %s", name, prev, file)
+			}
+			names[name] = file
+			info, err := fs.Lstat(stage, filepath.ToSlash(file))
+			if err != nil {
+				return nil, err
+			}
+			bins = append(bins, binFile{path: file, name: name, link: info.Mode()&fs.ModeSymlink != 0})
+		}
+	}
+
+	for _, bin := range bins {
+		dest := filepath.Join("bin", bin.name)
+		if dest == bin.path {
+			continue
+		}
+		for _, other := range bins {
+			if other.path == dest {
+				return nil, fmt.Errorf("moving %s to %s would overwrite %s", bin.path, dest, other.path)
+			}
+		}
+	}
+	var files, links []binFile
+	for _, bin := range bins {
+		if bin.link {
+			links = append(links, bin)
+		} else {
+			files = append(files, bin)
+		}
+	}
+	return append(files, links...), nil
+}
+
+// placeBins moves the bins found by findBins into bin/ and makes them
+// executable
+func placeBins(stageDir string, bins []binFile, r progress.Reporter) error {
+	destBinDir := filepath.Join(stageDir, "bin")
+	if err := os.MkdirAll(destBinDir, 0o755); err != nil {
+		return err
+	}
+	// Chmod through a root, so a symlinked binary can't lead it outside stageDir
+	stageRoot, err := os.OpenRoot(stageDir)
+	if err != nil {
+		return err
+	}
+	defer stageRoot.Close()
+	moved := make(map[string]string, len(bins))
+	for _, bin := range bins {
+		srcBin := filepath.Join(stageDir, bin.path)
+		destBin := filepath.Join(destBinDir, bin.name)
+		if srcBin != destBin {
+			r.Step(progress.EventLink, fmt.Sprintf("Moving %s to %s...", bin.path, filepath.Join("bin", bin.name)))
+			if err := moveExtracted(srcBin, destBin, moved); err != nil {
+				return err
+			}
+			moved[srcBin] = destBin
+		}
+
+		// Chmod +x (of the target, for a symlinked binary)
+		if err := stageRoot.Chmod(filepath.Join("bin", bin.name), 0o755); err != nil {
 			return err
 		}
-	} else if strings.HasSuffix(filename, ".tar.xz") {
-		if err := util.ExtractTarXz(downloadPath, installDir, plugin.StripComponents); err != nil {
+	}
+	return nil
+}
+
+// completionDirs are where each shell looks for completion scripts under a prefix
+var completionDirs = map[registry.Shell]string{
+	registry.ShellBash: filepath.Join("share", "bash-completion", "completions"),
+	registry.ShellZsh:  filepath.Join("share", "zsh", "site-functions"),
+	registry.ShellFish: filepath.Join("share", "fish", "vendor_completions.d"),
+}
+
+// completionName is the file name shell loads cmd's completions from
+func completionName(shell registry.Shell, cmd string) string {
+	switch shell {
+	case registry.ShellZsh:
+		return "_" + cmd
+	case registry.ShellFish:
+		return cmd + ".fish"
+	default:
+		return cmd
+	}
+}
+
+// manSection returns the section of a man page from its file name, e.g. "1"
+// for rg.1 or "3" for foo.3pm.gz, or "" if the name has none
+func manSection(name string) string {
+	ext := filepath.Ext(strings.TrimSuffix(name, ".gz"))
+	if len(ext) < 2 || ext[1] < '1' || ext[1] > '9' {
+		return ""
+	}
+	return ext[1:2]
+}
+
+// arrangeShareFiles moves the completion scripts and man pages found in the
+// extracted asset to where shells and man look for them under share/. A
+// single completion script is renamed for the command; several keep their
+// names. Paths matching nothing are skipped with a warning, since older
+// releases often lack them.
+func arrangeShareFiles(plugin *registry.PluginConfig, ctx Context, stageDir string, r progress.Reporter) error {
+	stage := os.DirFS(stageDir)
+	sources := make(map[string]string) // destination -> path
+	var dests []string                 // In the order found
+
+	add := func(file, dest string) error {
+		prev, ok := sources[dest]
+		switch {
+		case !ok:
+			sources[dest] = file
+			dests = append(dests, dest)
+		case prev != file:
+			return fmt.Errorf("%s would be installed from both %s and %s", dest, prev, file)
+		}
+		return nil
+	}
+
+	for _, shell := range registry.Shells {
+		tmpl, ok := plugin.Completions[shell]
+		if !ok {
+			continue
+		}
+		pattern, files, err := globFiles(stage, tmpl, ctx)
+		if err != nil {
+			return fmt.Errorf("%s completions: %w", shell, err)
+		}
+		if len(files) == 0 {
+			r.Logf("Warning: no %s completions at %s", shell, pattern)
+			continue
+		}
+		for _, file := range files {
+			name := filepath.Base(file)
+			if len(files) == 1 {
+				name = completionName(shell, plugin.Cmd)
+			}
+			if err := add(file, filepath.Join(completionDirs[shell], name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, tmpl := range plugin.ManPages {
+		pattern, files, err := globFiles(stage, tmpl, ctx)
+		if err != nil {
+			return fmt.Errorf("man page: %w", err)
+		}
+		if len(files) == 0 {
+			r.Logf("Warning: no man pages at %s", pattern)
+			continue
+		}
+		for _, file := range files {
+			section := manSection(filepath.Base(file))
+			if section == "" {
+				return fmt.Errorf("man page %s has no section suffix such as .1", file)
+			}
+			if err := add(file, filepath.Join("share", "man", "man"+section, filepath.Base(file))); err != nil {
+				return err
+			}
+		}
+	}
+
+	moved := make(map[string]string, len(dests))
+	for _, dest := range dests {
+		file := sources[dest]
+		if file == dest {
+			continue // Already in place, as in gh's share/man
+		}
+		src, dst := filepath.Join(stageDir, file), filepath.Join(stageDir, dest)
+		r.Step(progress.EventLink, fmt.Sprintf("Moving %s to %s...", file, dest))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
 			return err
 		}
-	} else if strings.HasSuffix(filename, ".gz") {
-		if err := util.ExtractGz(downloadPath, filepath.Join(installDir, relBinPath)); err != nil {
+		if err := moveExtracted(src, dst, moved); err != nil {
 			return err
 		}
-	} else if strings.HasSuffix(filename, ".zip") {
-		if err := util.ExtractZip(downloadPath, installDir, plugin.StripComponents); err != nil {
+		moved[src] = dst
+	}
+	return nil
+}
+
+// linkShareFiles links the completions and man pages under installDir's
+// share/ into shareDir, replacing links from other installs of the same files.
+// Links left dangling by removed installs are pruned.
+func linkShareFiles(installDir, shareDir string) error {
+	share := filepath.Join(installDir, "share")
+	dirs := []string{"man"}
+	for _, shell := range registry.Shells {
+		rel, err := filepath.Rel("share", completionDirs[shell])
+		if err != nil {
 			return err
 		}
-	} else {
-		// Assume it's a raw executable (like shfmt/gofumpt)
-		targetPath := filepath.Join(installDir, relBinPath)
-		if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
+		dirs = append(dirs, rel)
+	}
+
+	for _, dir := range dirs {
+		err := filepath.WalkDir(filepath.Join(share, dir), func(path string, d fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) && path == filepath.Join(share, dir) {
+				return fs.SkipDir // The tool ships none
+			}
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(share, path)
+			if err != nil {
+				return err
+			}
+			target, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			return replaceSymlink(target, filepath.Join(shareDir, rel))
+		})
+		if err != nil {
 			return err
 		}
-		if err := util.CopyFile(downloadPath, targetPath); err != nil {
+	}
+	for _, dir := range dirs {
+		if err := pruneDanglingLinks(filepath.Join(shareDir, dir)); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
-	// Locate binary and move to bin/
-	// (relBinPath is already rendered above)
+// pruneDanglingLinks removes the symlinks under dir whose target is gone,
+// e.g. because mise removed the install they pointed into
+func pruneDanglingLinks(dir string) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return err
+		}
+		if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil // No install has linked any yet
+	}
+	return err
+}
 
-	srcBin := filepath.Join(installDir, relBinPath)
-	destBinDir := filepath.Join(installDir, "bin")
-	if err := os.MkdirAll(destBinDir, 0o755); err != nil {
+// replaceSymlink atomically points the symlink link at target
+func replaceSymlink(target, link string) error {
+	if err := os.MkdirAll(filepath.Dir(link), 0o755); err != nil {
+		return err
+	}
+	tmp := fmt.Sprintf("%s.tmp-%d", link, os.Getpid())
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
 		return err
 	}
-	destBin := filepath.Join(destBinDir, plugin.Cmd)
+	return nil
+}
 
-	// Check if source exists
-	if _, err := os.Stat(srcBin); os.IsNotExist(err) {
-		return fmt.Errorf("binary not found at %s", srcBin)
+// globFiles renders a path template and returns the pattern and the files,
+// not directories, it matches in stage. Symlinks to files count as files.
+func globFiles(stage fs.FS, tmpl string, ctx Context) (string, []string, error) {
+	pattern, err := renderTemplate(tmpl, ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	if !fs.ValidPath(pattern) {
+		return pattern, nil, fmt.Errorf("%q is outside the install directory", pattern)
+	}
+	matches, err := fs.Glob(stage, pattern)
+	if err != nil {
+		return pattern, nil, fmt.Errorf("%q: %w", pattern, err)
 	}
 
-	if srcBin != destBin {
-		fmt.Printf("Moving %s to %s...\n", srcBin, destBin)
-		if err := os.Rename(srcBin, destBin); err != nil {
-			return err
+	var files []string
+	for _, m := range matches {
+		if info, err := fs.Stat(stage, m); err == nil && !info.IsDir() {
+			files = append(files, filepath.FromSlash(m))
 		}
 	}
+	return pattern, files, nil
+}
 
-	// Chmod +x
-	if err := os.Chmod(destBin, 0o755); err != nil {
+// moveExtracted renames src to dst. A file shipped as a relative symlink is
+// recreated at dst pointing to the same file, since the rename alone would
+// break its target, or to where moved, mapping sources to destinations, put it.
+func moveExtracted(src, dst string, moved map[string]string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
 		return err
 	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return os.Rename(src, dst)
+	}
 
-	return nil
+	target, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(target) {
+		abs := filepath.Join(filepath.Dir(src), target)
+		if dest, ok := moved[abs]; ok {
+			abs = dest
+		}
+		if target, err = filepath.Rel(filepath.Dir(dst), abs); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(dst); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Symlink(target, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
 }
 
 func renderTemplate(tmplStr string, data any) (string, error) {
@@ -165,20 +743,12 @@ func renderTemplate(tmplStr string, data any) (string, error) {
 }
 
 func verifyChecksum(filepath, expected string) error {
-	f, err := os.Open(filepath)
+	actual, err := cache.FileSHA256(filepath)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-
-	hasher := sha256.New()
-	if _, err := io.Copy(hasher, f); err != nil {
-		return err
-	}
-
-	actual := hex.EncodeToString(hasher.Sum(nil))
 	if actual != expected {
-		return fmt.Errorf("expected %s, got %s", expected, actual)
+		return fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, expected, actual)
 	}
 	return nil
 }
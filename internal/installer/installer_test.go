@@ -0,0 +1,315 @@
+package installer
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aniaan/sous-chef/internal/progress"
+	"github.com/aniaan/sous-chef/internal/registry"
+)
+
+func TestFindBins(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string // Extracted files, relative to the staging directory
+		bins    []registry.BinConfig
+		want    []string // Names in bin/
+		wantErr string   // Substring of the expected error
+		wantIs  error
+	}{
+		{
+			name:  "nested path",
+			files: []string{"tool-1.0.0/bin/tool", "tool-1.0.0/README.md"},
+			bins:  []registry.BinConfig{{PathTemplate: "tool-{{.Version}}/bin/tool"}},
+			want:  []string{"tool"},
+		},
+		{
+			name:  "glob in a nested directory",
+			files: []string{"pkg/libexec/bin/tool", "pkg/libexec/bin/toolctl", "pkg/share/doc"},
+			bins:  []registry.BinConfig{{PathTemplate: "pkg/*/bin/*"}},
+			want:  []string{"tool", "toolctl"},
+		},
+		{
+			name:  "renamed",
+			files: []string{"dist/tool-linux-amd64"},
+			bins:  []registry.BinConfig{{PathTemplate: "dist/tool-*", Name: "tool"}},
+			want:  []string{"tool"},
+		},
+		{
+			name:    "missing bin",
+			files:   []string{"bin/tool"},
+			bins:    []registry.BinConfig{{PathTemplate: "bin/tool"}, {PathTemplate: "libexec/helper"}},
+			wantErr: "libexec/helper",
+			wantIs:  ErrBinaryNotFound,
+		},
+		{
+			name:    "missing named bin",
+			files:   []string{"bin/tool"},
+			bins:    []registry.BinConfig{{PathTemplate: "bin/tool"}, {PathTemplate: "dist/helper-*", Name: "helper"}},
+			wantErr: "helper at dist/helper-*",
+			wantIs:  ErrBinaryNotFound,
+		},
+		{
+			name:    "name clash",
+			files:   []string{"a/tool", "b/tool"},
+			bins:    []registry.BinConfig{{PathTemplate: "*/tool"}},
+			wantErr: "bin/tool would be installed from both",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stageDir := t.TempDir()
+			for _, file := range tt.files {
+				path := filepath.Join(stageDir, filepath.FromSlash(file))
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				// Archives don't always mark binaries executable
+				if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			bins, err := findBins(tt.bins, Context{Version: "1.0.0"}, stageDir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("findBins() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+					t.Errorf("findBins() error = %v, want %v", err, tt.wantIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("findBins() error = %v", err)
+			}
+
+			if err := placeBins(stageDir, bins, progress.New(progress.None, io.Discard)); err != nil {
+				t.Fatalf("placeBins() error = %v", err)
+			}
+			entries, err := os.ReadDir(filepath.Join(stageDir, "bin"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name())
+				info, err := e.Info()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if perm := info.Mode().Perm(); perm != 0o755 {
+					t.Errorf("bin/%s has mode %o, want 755", e.Name(), perm)
+				}
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("bin/ holds %q, want %q", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestArrangeShareFiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       []string
+		completions map[registry.Shell]string
+		manPages    []string
+		want        []string // Files under share/
+		wantErr     string
+	}{
+		{
+			name:  "single completion per shell",
+			files: []string{"complete/tool.bash", "complete/_tool", "complete/tool.fish"},
+			completions: map[registry.Shell]string{
+				registry.ShellBash: "complete/tool.bash",
+				registry.ShellZsh:  "complete/_tool",
+				registry.ShellFish: "complete/*.fish",
+			},
+			want: []string{
+				"share/bash-completion/completions/tool",
+				"share/fish/vendor_completions.d/tool.fish",
+				"share/zsh/site-functions/_tool",
+			},
+		},
+		{
+			name:        "several completions keep their names",
+			files:       []string{"completions/tool", "completions/toolctl"},
+			completions: map[registry.Shell]string{registry.ShellBash: "completions/*"},
+			want: []string{
+				"share/bash-completion/completions/tool",
+				"share/bash-completion/completions/toolctl",
+			},
+		},
+		{
+			name:     "man pages by section",
+			files:    []string{"tool-{{.Version}}/doc/tool.1", "tool-{{.Version}}/doc/tool-config.5.gz"},
+			manPages: []string{"tool-{{.Version}}/doc/*.1", "tool-{{.Version}}/doc/*.gz"},
+			want:     []string{"share/man/man1/tool.1", "share/man/man5/tool-config.5.gz"},
+		},
+		{
+			name:     "already in place",
+			files:    []string{"share/man/man1/tool.1"},
+			manPages: []string{"share/man/man1/*"},
+			want:     []string{"share/man/man1/tool.1"},
+		},
+		{
+			name:        "missing paths are skipped",
+			files:       []string{"bin/tool"},
+			completions: map[registry.Shell]string{registry.ShellZsh: "completions/_tool"},
+			manPages:    []string{"man/*.1"},
+		},
+		{
+			name:     "man page without a section",
+			files:    []string{"doc/tool.md"},
+			manPages: []string{"doc/tool.md"},
+			wantErr:  "no section suffix",
+		},
+		{
+			name:        "clashing completions",
+			files:       []string{"a/tool", "b/tool"},
+			completions: map[registry.Shell]string{registry.ShellBash: "*/tool"},
+			wantErr:     "would be installed from both",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := Context{Version: "1.0.0"}
+			stageDir := t.TempDir()
+			for _, file := range tt.files {
+				file = strings.ReplaceAll(file, "{{.Version}}", ctx.Version)
+				path := filepath.Join(stageDir, filepath.FromSlash(file))
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			plugin := &registry.PluginConfig{Cmd: "tool", Completions: tt.completions, ManPages: tt.manPages}
+			err := arrangeShareFiles(plugin, ctx, stageDir, progress.New(progress.None, io.Discard))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("arrangeShareFiles() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("arrangeShareFiles() error = %v", err)
+			}
+
+			var got []string
+			err = filepath.WalkDir(filepath.Join(stageDir, "share"), func(path string, d fs.DirEntry, err error) error {
+				if errors.Is(err, fs.ErrNotExist) {
+					return filepath.SkipDir
+				}
+				if err != nil || d.IsDir() {
+					return err
+				}
+				rel, err := filepath.Rel(stageDir, path)
+				got = append(got, filepath.ToSlash(rel))
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("share/ holds %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkShareFiles(t *testing.T) {
+	root := t.TempDir()
+	shareDir := filepath.Join(root, "share")
+	install := func(version string, files ...string) string {
+		t.Helper()
+		dir := filepath.Join(root, "installs", version)
+		for _, file := range files {
+			path := filepath.Join(dir, "share", filepath.FromSlash(file))
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(version), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+	// links maps the files under shareDir to the installs they resolve into
+	links := func() map[string]string {
+		t.Helper()
+		got := map[string]string{}
+		err := filepath.WalkDir(shareDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(shareDir, path)
+			if err != nil {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				data = []byte("dangling")
+			} else if err != nil {
+				return err
+			}
+			got[filepath.ToSlash(rel)] = string(data)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	check := func(want map[string]string) {
+		t.Helper()
+		if got := links(); !maps.Equal(got, want) {
+			t.Errorf("share directory links %v, want %v", got, want)
+		}
+	}
+
+	v1 := install("1.0.0", "man/man1/tool.1", "man/man1/tool-old.1", "zsh/site-functions/_tool", "doc/README")
+	if err := linkShareFiles(v1, shareDir); err != nil {
+		t.Fatalf("linkShareFiles() error = %v", err)
+	}
+	check(map[string]string{
+		"man/man1/tool.1":          "1.0.0",
+		"man/man1/tool-old.1":      "1.0.0",
+		"zsh/site-functions/_tool": "1.0.0",
+	})
+
+	// A newer install takes over the files both ship
+	v2 := install("2.0.0", "man/man1/tool.1", "zsh/site-functions/_tool")
+	if err := linkShareFiles(v2, shareDir); err != nil {
+		t.Fatalf("linkShareFiles() error = %v", err)
+	}
+	check(map[string]string{
+		"man/man1/tool.1":          "2.0.0",
+		"man/man1/tool-old.1":      "1.0.0",
+		"zsh/site-functions/_tool": "2.0.0",
+	})
+
+	// Links into a removed install are pruned by the next one
+	if err := os.RemoveAll(v1); err != nil {
+		t.Fatal(err)
+	}
+	if err := linkShareFiles(v2, shareDir); err != nil {
+		t.Fatalf("linkShareFiles() error = %v", err)
+	}
+	check(map[string]string{
+		"man/man1/tool.1":          "2.0.0",
+		"zsh/site-functions/_tool": "2.0.0",
+	})
+}
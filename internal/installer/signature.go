@@ -0,0 +1,120 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"github.com/aniaan/sous-chef/internal/cache"
+	"github.com/aniaan/sous-chef/internal/registry"
+	"github.com/aniaan/sous-chef/internal/source"
+	"github.com/aniaan/sous-chef/internal/verify"
+)
+
+// verifySignature checks the downloaded asset against the plugin's signature
+// config. It returns an error wrapping verify.ErrSignatureMissing when nothing
+// is published, and verify.ErrSignatureInvalid when verification fails.
+func verifySignature(src source.Source, plugin *registry.PluginConfig, ctx Context, tag, filename, assetPath, tempDir string) error {
+	cfg := plugin.Signature
+
+	if cfg.Type == registry.SignatureMinisign {
+		sigData, err := fetchSignatureAsset(src, plugin, ctx, tag, filename, tempDir)
+		if err != nil {
+			return err
+		}
+		return verify.Minisign(assetPath, sigData, cfg.PublicKey)
+	}
+
+	policy, err := signaturePolicy(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid signature config: %w", err)
+	}
+	hexDigest, err := cache.FileSHA256(assetPath)
+	if err != nil {
+		return err
+	}
+	digest, err := verify.SHA256(hexDigest)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Type == registry.SignatureCosign {
+		sigData, err := fetchSignatureAsset(src, plugin, ctx, tag, filename, tempDir)
+		if err != nil {
+			return err
+		}
+		return verify.Cosign(digest, sigData, policy)
+	}
+
+	github, ok := src.(*source.GitHub)
+	if !ok {
+		return fmt.Errorf("%w: github-attestation requires a GitHub source", verify.ErrSignatureInvalid)
+	}
+	bundles, err := github.Attestations(plugin.Repo, hexDigest)
+	if errors.Is(err, source.ErrNotFound) {
+		return fmt.Errorf("%w: no attestations for %s", verify.ErrSignatureMissing, filename)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch attestations: %w", err)
+	}
+
+	// Any attestation that verifies is enough
+	var lastErr error
+	for _, bundle := range bundles {
+		if lastErr = verify.Bundle(digest, bundle, policy); lastErr == nil {
+			return nil
+		}
+	}
+	return lastErr
+}
+
+// fetchSignatureAsset downloads the signature asset into tempDir and returns its contents
+func fetchSignatureAsset(src source.Source, plugin *registry.PluginConfig, ctx Context, tag, filename, tempDir string) ([]byte, error) {
+	name, err := renderTemplate(plugin.Signature.AssetTemplate, assetContext{Context: ctx, Asset: filename})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render signature asset name: %w", err)
+	}
+
+	dest := filepath.Join(tempDir, path.Base(name))
+	err = src.DownloadAsset(plugin.Repo, tag, name, dest)
+	if errors.Is(err, source.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s not found", verify.ErrSignatureMissing, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	return os.ReadFile(dest)
+}
+
+func signaturePolicy(cfg *registry.SignatureConfig) (verify.Policy, error) {
+	var policy verify.Policy
+	if cfg.PublicKey != "" {
+		key, err := verify.ParsePublicKey([]byte(cfg.PublicKey))
+		if err != nil {
+			return policy, err
+		}
+		policy.PublicKey = key
+		return policy, nil
+	}
+
+	var err error
+	if cfg.TrustedRoot != "" {
+		policy.Root, err = verify.LoadTrustedRoot(cfg.TrustedRoot)
+	} else {
+		policy.Root, err = verify.PublicGoodRoot()
+	}
+	if err != nil {
+		return policy, err
+	}
+	policy.Identity = cfg.CertificateIdentity
+	policy.Issuer = cfg.CertificateOIDCIssuer
+	if cfg.CertificateIdentityRegexp != "" {
+		if policy.IdentityRegexp, err = regexp.Compile(cfg.CertificateIdentityRegexp); err != nil {
+			return policy, err
+		}
+	}
+	return policy, nil
+}
@@ -0,0 +1,76 @@
+package installer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aniaan/sous-chef/internal/gh"
+	"github.com/aniaan/sous-chef/internal/registry"
+	"github.com/aniaan/sous-chef/internal/source"
+	"github.com/aniaan/sous-chef/internal/verify"
+)
+
+// TestRegistryAttestations verifies the GitHub attestations of the latest
+// release assets of the registry tools that declare a github-attestation
+// policy. It talks to GitHub, so it only runs with SOUS_CHEF_NETWORK_TESTS=1.
+func TestRegistryAttestations(t *testing.T) {
+	if os.Getenv("SOUS_CHEF_NETWORK_TESTS") == "" {
+		t.Skip("set SOUS_CHEF_NETWORK_TESTS=1 to fetch attestations from GitHub")
+	}
+	plugins, err := registry.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := gh.NewClient()
+
+	for _, name := range []string{"uv", "ty"} {
+		t.Run(name, func(t *testing.T) {
+			plugin := plugins[name]
+			if plugin.Signature == nil || plugin.Signature.Type != registry.SignatureGitHubAttestation {
+				t.Fatalf("%s has no github-attestation policy", name)
+			}
+			policy, err := signaturePolicy(plugin.Signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+			src := source.Open(client, plugin.Source).(*source.GitHub)
+			releases, err := plugin.GetReleases(src, 1)
+			if err != nil || len(releases) == 0 {
+				t.Fatalf("GetReleases() = %d releases, %v", len(releases), err)
+			}
+
+			// A few assets keep unauthenticated runs within the rate limit
+			checked := 0
+			for _, asset := range releases[0].Assets {
+				if checked == 3 {
+					break
+				}
+				hexDigest, ok := strings.CutPrefix(asset.Digest, "sha256:")
+				if !ok || !strings.HasSuffix(asset.Name, ".tar.gz") {
+					continue
+				}
+				digest, err := verify.SHA256(hexDigest)
+				if err != nil {
+					t.Fatal(err)
+				}
+				bundles, err := src.Attestations(plugin.Repo, hexDigest)
+				if err != nil {
+					t.Fatalf("%s: %v", asset.Name, err)
+				}
+				for _, bundle := range bundles {
+					if err = verify.Bundle(digest, bundle, policy); err == nil {
+						break
+					}
+				}
+				if err != nil {
+					t.Errorf("%s@%s: %v", asset.Name, releases[0].TagName, err)
+				}
+				checked++
+			}
+			if checked == 0 {
+				t.Fatalf("%s has no .tar.gz assets with a digest", releases[0].TagName)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/aniaan/sous-chef/internal/progress"
+)
+
+// installLockPath returns the lock file guarding installDir. It lives next to
+// installDir because installDir itself is replaced on commit.
+func installLockPath(installDir string) string {
+	absDir, err := filepath.Abs(installDir)
+	if err != nil {
+		absDir = filepath.Clean(installDir)
+	}
+	return filepath.Join(filepath.Dir(absDir), "."+filepath.Base(absDir)+".lock")
+}
+
+// newStagingDir creates an empty directory next to installDir, on the same
+// filesystem so it can later be renamed into place.
+func newStagingDir(installDir string) (string, error) {
+	absDir, err := filepath.Abs(installDir)
+	if err != nil {
+		return "", err
+	}
+	parent := filepath.Dir(absDir)
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return "", err
+	}
+	stageDir, err := os.MkdirTemp(parent, "."+filepath.Base(absDir)+".staging-")
+	if err != nil {
+		return "", err
+	}
+	// MkdirTemp creates 0700 directories; installs should be world-readable
+	if err := os.Chmod(stageDir, 0o755); err != nil {
+		os.RemoveAll(stageDir)
+		return "", err
+	}
+	return stageDir, nil
+}
+
+// commitStaging replaces installDir with stageDir. An existing installDir is
+// moved aside first and restored if the final rename fails, so a failed
+// install never leaves installDir half-populated.
+func commitStaging(stageDir, installDir string, r progress.Reporter) error {
+	absDir, err := filepath.Abs(installDir)
+	if err != nil {
+		return err
+	}
+
+	backup := ""
+	if _, err := os.Lstat(absDir); err == nil {
+		backup = filepath.Join(filepath.Dir(absDir), "."+filepath.Base(absDir)+".old-"+filepath.Base(stageDir))
+		if err := os.Rename(absDir, backup); err != nil {
+			return fmt.Errorf("failed to move aside existing install: %w", err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := os.Rename(stageDir, absDir); err != nil {
+		if backup != "" {
+			if rerr := os.Rename(backup, absDir); rerr != nil {
+				return fmt.Errorf("failed to install: %w (and failed to restore previous install from %s: %v)", err, backup, rerr)
+			}
+		}
+		return fmt.Errorf("failed to install: %w", err)
+	}
+
+	if backup != "" {
+		if err := os.RemoveAll(backup); err != nil {
+			r.Logf("Warning: failed to remove previous install at %s: %v", backup, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package installer
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aniaan/sous-chef/internal/lock"
+	"github.com/aniaan/sous-chef/internal/progress"
+	"github.com/aniaan/sous-chef/internal/registry"
+)
+
+// writeInstall creates dir holding a bin/tool file with content
+func writeInstall(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bin", "tool"), []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readInstall returns the content of dir's bin/tool
+func readInstall(t *testing.T, dir string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "bin", "tool"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCommitStagingReplacesInstall(t *testing.T) {
+	root := t.TempDir()
+	installDir := filepath.Join(root, "1.0.0")
+	writeInstall(t, installDir, "old")
+
+	stageDir, err := newStagingDir(installDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeInstall(t, stageDir, "new")
+
+	if err := commitStaging(stageDir, installDir, progress.New(progress.None, io.Discard)); err != nil {
+		t.Fatalf("commitStaging() error = %v", err)
+	}
+	if got := readInstall(t, installDir); got != "new" {
+		t.Errorf("installed %q, want %q", got, "new")
+	}
+
+	// Neither the staging directory nor the moved-aside install is left behind
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("%d entries left next to the install, want only the install", len(entries))
+	}
+}
+
+func TestCommitStagingRestoresOnFailure(t *testing.T) {
+	root := t.TempDir()
+	installDir := filepath.Join(root, "1.0.0")
+	writeInstall(t, installDir, "old")
+
+	// A staging directory that doesn't exist makes the rename into place fail
+	// after the existing install has been moved aside
+	stageDir := filepath.Join(root, ".1.0.0.staging-missing")
+	if err := commitStaging(stageDir, installDir, progress.New(progress.None, io.Discard)); err == nil {
+		t.Fatal("commitStaging() succeeded with a missing staging directory")
+	}
+	if got := readInstall(t, installDir); got != "old" {
+		t.Errorf("install holds %q after a failed commit, want the previous %q", got, "old")
+	}
+	if _, err := os.Stat(filepath.Join(root, ".1.0.0.old-.1.0.0.staging-missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("moved-aside install left behind: %v", err)
+	}
+}
+
+func TestInstallWaitsForInstallLock(t *testing.T) {
+	installDir := filepath.Join(t.TempDir(), "1.0.0")
+	held, err := lock.Acquire(installLockPath(installDir), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Release()
+
+	plugin := &registry.PluginConfig{Repo: "owner/tool", AssetTemplate: "tool"}
+	_, err = Install(plugin, "1.0.0", installDir, Options{LockTimeout: 300 * time.Millisecond})
+	if !errors.Is(err, lock.ErrTimeout) {
+		t.Fatalf("Install() error = %v, want %v", err, lock.ErrTimeout)
+	}
+	if _, err := os.Stat(installDir); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("install directory touched while locked: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+// Package lock provides advisory inter-process file locks.
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is how long Acquire waits for a lock by default
+const DefaultTimeout = 10 * time.Minute
+
+const pollInterval = 200 * time.Millisecond
+
+// ErrTimeout is returned when a lock could not be acquired in time
+var ErrTimeout = errors.New("timed out waiting for lock")
+
+// Lock is a held advisory lock on a file
+type Lock struct {
+	f *os.File
+}
+
+// Acquire takes an exclusive lock on path, creating it if needed, and records
+// the current pid in it. If another process holds the lock, Acquire prints a
+// message naming that pid and waits up to timeout.
+func Acquire(path string, timeout time.Duration) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	waiting := false
+	for {
+		ok, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if ok {
+			break
+		}
+
+		holder := readPID(path)
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%w on %s held by pid %s after %s", ErrTimeout, path, holder, timeout)
+		}
+		if !waiting {
+			fmt.Fprintf(os.Stderr, "Waiting for lock on %s held by pid %s...\n", path, holder)
+			waiting = true
+		}
+		time.Sleep(pollInterval)
+	}
+
+	// Best effort: the pid is informational only
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &Lock{f: f}, nil
+}
+
+// Release unlocks and closes the lock file. The file itself is left in place
+// so that concurrent waiters keep locking the same inode.
+func (l *Lock) Release() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	err := unlock(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
+
+func readPID(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "unknown"
+	}
+	pid := strings.TrimSpace(string(data))
+	if pid == "" {
+		return "unknown"
+	}
+	return pid
+}
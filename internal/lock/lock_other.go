@@ -0,0 +1,15 @@
+//go:build !unix
+
+package lock
+
+import "os"
+
+// Locking is a no-op on platforms without flock; sous-chef only supports
+// installing on darwin and linux.
+func tryLock(f *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlock(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,185 @@
+// Package output renders command results as human text, a JSON document or
+// newline-delimited JSON records.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Format selects how results are written
+type Format string
+
+const (
+	Text   Format = "text"   // Human-readable lines
+	JSON   Format = "json"   // One JSON document: an array for lists, an object otherwise
+	NDJSON Format = "ndjson" // One JSON object per line
+)
+
+// Error codes reported in JSON error objects. They are part of the CLI
+// contract and must not change.
+const (
+	CodeError               = "error"
+	CodeUsage               = "usage"
+	CodeRegistry            = "registry_error"
+	CodeConfig              = "config_error"
+	CodeUnknownTool         = "unknown_tool"
+	CodeUnsupportedPlatform = "unsupported_platform"
+	CodeNotFound            = "not_found"
+	CodeNoMatchingRelease   = "no_matching_release"
+	CodeRateLimited         = "rate_limited"
+	CodeFetchFailed         = "fetch_failed"
+	CodeInstallFailed       = "install_failed"
+	CodeChecksum            = "checksum_failed"
+	CodeSignature           = "signature_failed"
+	CodeBinaryNotFound      = "binary_not_found"
+	CodeLockTimeout         = "lock_timeout"
+	CodeOffline             = "offline"
+)
+
+// Process exit codes, documented in the README
+const (
+	ExitOK                  = 0
+	ExitError               = 1 // Any failure without a more specific code
+	ExitUsage               = 2
+	ExitUnknownTool         = 3
+	ExitUnsupportedPlatform = 4
+	ExitNotFound            = 5 // No such release or asset, or no version matches
+	ExitRateLimited         = 6
+	ExitChecksum            = 7
+	ExitSignature           = 8
+	ExitBinaryNotFound      = 9
+	ExitLockTimeout         = 10
+	ExitOffline             = 11 // Offline and the cache or local mirror lacks something
+)
+
+// ExitCode returns the process exit code for an error code
+func ExitCode(code string) int {
+	switch code {
+	case CodeUsage:
+		return ExitUsage
+	case CodeUnknownTool:
+		return ExitUnknownTool
+	case CodeUnsupportedPlatform:
+		return ExitUnsupportedPlatform
+	case CodeNotFound, CodeNoMatchingRelease:
+		return ExitNotFound
+	case CodeRateLimited:
+		return ExitRateLimited
+	case CodeChecksum:
+		return ExitChecksum
+	case CodeSignature:
+		return ExitSignature
+	case CodeBinaryNotFound:
+		return ExitBinaryNotFound
+	case CodeLockTimeout:
+		return ExitLockTimeout
+	case CodeOffline:
+		return ExitOffline
+	}
+	return ExitError
+}
+
+// ParseFormat validates a format name; empty means Text
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case Text, JSON, NDJSON:
+		return f, nil
+	case "":
+		return Text, nil
+	}
+	return "", fmt.Errorf("unknown output format %q (want text, json or ndjson)", s)
+}
+
+// Structured reports whether f emits JSON
+func (f Format) Structured() bool {
+	return f == JSON || f == NDJSON
+}
+
+// Writer emits records in a Format. In JSON format, records are buffered
+// and written as an array by Flush.
+type Writer struct {
+	format  Format
+	out     io.Writer
+	errOut  io.Writer
+	records []any
+}
+
+// NewWriter returns a Writer with results on stdout and errors on stderr
+func NewWriter(format Format) *Writer {
+	return &Writer{format: format, out: os.Stdout, errOut: os.Stderr}
+}
+
+// Format returns the writer's format
+func (w *Writer) Format() Format {
+	return w.format
+}
+
+// Record emits one structured record. It is ignored in Text format, where
+// commands print their own lines.
+func (w *Writer) Record(v any) {
+	switch w.format {
+	case NDJSON:
+		json.NewEncoder(w.out).Encode(v)
+	case JSON:
+		w.records = append(w.records, v)
+	}
+}
+
+// Flush writes buffered JSON records as an array
+func (w *Writer) Flush() {
+	if w.format != JSON {
+		return
+	}
+	records := w.records
+	if records == nil {
+		records = []any{}
+	}
+	writeIndented(w.out, records)
+	w.records = nil
+}
+
+// Object writes a single result object, as-is in JSON and NDJSON format
+func (w *Writer) Object(v any) {
+	switch w.format {
+	case NDJSON:
+		json.NewEncoder(w.out).Encode(v)
+	case JSON:
+		writeIndented(w.out, v)
+	}
+}
+
+// ErrorObject is the JSON form of a failed command
+type ErrorObject struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail carries a stable code and a human-readable message
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Tool    string `json:"tool,omitempty"`
+}
+
+// Error reports a failure on stderr: as a JSON error object in structured
+// formats, otherwise as a plain "Error: ..." line
+func (w *Writer) Error(code, tool string, err error) {
+	if !w.format.Structured() {
+		fmt.Fprintf(w.errOut, "Error: %v\n", err)
+		return
+	}
+	obj := ErrorObject{Error: ErrorDetail{Code: code, Message: err.Error(), Tool: tool}}
+	if w.format == NDJSON {
+		json.NewEncoder(w.errOut).Encode(obj)
+		return
+	}
+	writeIndented(w.errOut, obj)
+}
+
+func writeIndented(out io.Writer, v any) {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
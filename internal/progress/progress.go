@@ -0,0 +1,283 @@
+// Package progress reports install progress as a terminal bar, plain log
+// lines, or newline-delimited JSON events.
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mode selects how progress is rendered
+type Mode string
+
+const (
+	Auto  Mode = "auto"  // Bar on a TTY, Plain otherwise
+	Bar   Mode = "bar"   // Redrawn progress bar
+	Plain Mode = "plain" // Periodic progress lines
+	JSON  Mode = "json"  // Newline-delimited JSON events
+	None  Mode = "none"  // Status messages only
+)
+
+// Event kinds emitted in JSON mode
+const (
+	EventLog              = "log"
+	EventDownloadStart    = "download_start"
+	EventDownloadProgress = "download_progress"
+	EventDownloadDone     = "download_done"
+	EventVerify           = "verify"
+	EventExtract          = "extract"
+	EventLink             = "link"
+)
+
+const (
+	barInterval   = 100 * time.Millisecond
+	plainInterval = 5 * time.Second
+	barWidth      = 30
+)
+
+// Reporter receives status messages, lifecycle steps and download progress.
+// Implementations are safe for concurrent use.
+type Reporter interface {
+	// Logf reports a human-readable status message
+	Logf(format string, args ...any)
+	// Step reports a lifecycle step (EventVerify, EventExtract or EventLink)
+	Step(kind, message string)
+	DownloadStart(name string, total int64)
+	DownloadProgress(name string, done, total int64)
+	DownloadDone(name string, size int64)
+}
+
+// ParseMode validates a mode name
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case Auto, Bar, Plain, JSON, None:
+		return m, nil
+	case "":
+		return Auto, nil
+	}
+	return "", fmt.Errorf("unknown progress mode %q (want auto, bar, plain, json or none)", s)
+}
+
+// New returns a Reporter for mode. JSON events go to out; status messages
+// and progress in the other modes go to stderr, keeping stdout for the
+// command's result.
+func New(mode Mode, out io.Writer) Reporter {
+	if mode == Auto {
+		mode = Plain
+		if isTerminal(os.Stderr) {
+			mode = Bar
+		}
+	}
+	return newReporter(mode, out, os.Stderr)
+}
+
+func newReporter(mode Mode, out, stderr io.Writer) Reporter {
+	if mode == JSON {
+		return &jsonReporter{enc: json.NewEncoder(out)}
+	}
+	return &textReporter{mode: mode, out: stderr}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// textReporter prints status lines and renders progress as a bar or as
+// periodic plain lines
+type textReporter struct {
+	mu   sync.Mutex
+	mode Mode
+	out  io.Writer
+
+	name     string
+	start    time.Time
+	startAt  int64 // Bytes already present when the download started
+	last     time.Time
+	barDrawn bool
+}
+
+func (r *textReporter) Logf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clearBar()
+	fmt.Fprintf(r.out, format+"\n", args...)
+}
+
+func (r *textReporter) Step(kind, message string) {
+	r.Logf("%s", message)
+}
+
+func (r *textReporter) DownloadStart(name string, total int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.name = name
+	r.start = time.Now()
+	r.startAt = -1
+	r.last = time.Time{}
+}
+
+func (r *textReporter) DownloadProgress(name string, done, total int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.mode == None {
+		return
+	}
+	if r.startAt < 0 {
+		r.startAt = done
+	}
+
+	interval := plainInterval
+	if r.mode == Bar {
+		interval = barInterval
+	}
+	now := time.Now()
+	if now.Sub(r.last) < interval {
+		return
+	}
+	r.last = now
+
+	status := r.status(done, total)
+	if r.mode == Bar {
+		fmt.Fprintf(r.out, "\r\033[K%s %s", renderBar(done, total), status)
+		r.barDrawn = true
+	} else {
+		fmt.Fprintf(r.out, "Downloading %s: %s\n", name, status)
+	}
+}
+
+func (r *textReporter) DownloadDone(name string, size int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clearBar()
+	if r.mode != None {
+		fmt.Fprintf(r.out, "Downloaded %s (%s in %s)\n", name, FormatBytes(size), time.Since(r.start).Round(100*time.Millisecond))
+	}
+}
+
+func (r *textReporter) clearBar() {
+	if r.barDrawn {
+		fmt.Fprint(r.out, "\r\033[K")
+		r.barDrawn = false
+	}
+}
+
+// status renders "done / total, rate, ETA"
+func (r *textReporter) status(done, total int64) string {
+	var b strings.Builder
+	b.WriteString(FormatBytes(done))
+	if total > 0 {
+		fmt.Fprintf(&b, " / %s (%d%%)", FormatBytes(total), done*100/total)
+	}
+
+	elapsed := time.Since(r.start).Seconds()
+	if elapsed > 0 {
+		rate := float64(done-max(r.startAt, 0)) / elapsed
+		fmt.Fprintf(&b, ", %s/s", FormatBytes(int64(rate)))
+		if total > 0 && rate > 0 {
+			eta := time.Duration(float64(total-done) / rate * float64(time.Second))
+			fmt.Fprintf(&b, ", ETA %s", eta.Round(time.Second))
+		}
+	}
+	return b.String()
+}
+
+func renderBar(done, total int64) string {
+	if total <= 0 {
+		return "[" + strings.Repeat("?", barWidth) + "]"
+	}
+	filled := int(min(done*barWidth/total, barWidth))
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled) + "]"
+}
+
+// jsonEvent is one line of JSON output
+type jsonEvent struct {
+	Event   string    `json:"event"`
+	Time    time.Time `json:"time"`
+	Message string    `json:"message,omitempty"`
+	Name    string    `json:"name,omitempty"`
+	Bytes   *int64    `json:"bytes,omitempty"`
+	Total   *int64    `json:"total,omitempty"`
+	Rate    *float64  `json:"bytes_per_second,omitempty"`
+}
+
+// jsonReporter writes each call as a JSON event, throttling progress events
+type jsonReporter struct {
+	mu    sync.Mutex
+	enc   *json.Encoder
+	start time.Time
+	base  int64
+	last  time.Time
+}
+
+func (r *jsonReporter) emit(e jsonEvent) {
+	e.Time = time.Now().UTC()
+	r.enc.Encode(e)
+}
+
+func (r *jsonReporter) Logf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.emit(jsonEvent{Event: EventLog, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *jsonReporter) Step(kind, message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.emit(jsonEvent{Event: kind, Message: message})
+}
+
+func (r *jsonReporter) DownloadStart(name string, total int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.start, r.base, r.last = time.Now(), -1, time.Time{}
+	r.emit(jsonEvent{Event: EventDownloadStart, Name: name, Total: sizePtr(total)})
+}
+
+func (r *jsonReporter) DownloadProgress(name string, done, total int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.base < 0 {
+		r.base = done
+	}
+	if time.Since(r.last) < time.Second {
+		return
+	}
+	r.last = time.Now()
+
+	rate := float64(done-r.base) / time.Since(r.start).Seconds()
+	r.emit(jsonEvent{Event: EventDownloadProgress, Name: name, Bytes: &done, Total: sizePtr(total), Rate: &rate})
+}
+
+func (r *jsonReporter) DownloadDone(name string, size int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.emit(jsonEvent{Event: EventDownloadDone, Name: name, Bytes: &size})
+}
+
+// sizePtr omits unknown (negative) sizes from JSON
+func sizePtr(n int64) *int64 {
+	if n < 0 {
+		return nil
+	}
+	return &n
+}
+
+// FormatBytes renders a byte count with binary units
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,36 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReporterStreams(t *testing.T) {
+	tests := []struct {
+		mode                Mode
+		wantOut, wantStderr bool
+	}{
+		{Bar, false, true},
+		{Plain, false, true},
+		{None, false, true},
+		{JSON, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			var out, stderr bytes.Buffer
+			r := newReporter(tt.mode, &out, &stderr)
+			r.DownloadStart("tool.tar.gz", 100)
+			r.DownloadProgress("tool.tar.gz", 50, 100)
+			r.DownloadDone("tool.tar.gz", 100)
+			r.Logf("Installed %s", "tool")
+
+			if got := out.Len() > 0; got != tt.wantOut {
+				t.Errorf("wrote to out = %v, want %v: %q", got, tt.wantOut, out.String())
+			}
+			if got := strings.Contains(stderr.String(), "Installed tool"); got != tt.wantStderr {
+				t.Errorf("status on stderr = %v, want %v: %q", got, tt.wantStderr, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,561 @@
+package registry
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/aniaan/sous-chef/internal/source"
+	"github.com/aniaan/sous-chef/internal/util"
+)
+
+//go:embed registry.toml
+var defaultRegistry []byte
+
+// UserRegistryEnv overrides the location of the user registry file.
+const UserRegistryEnv = "SOUS_CHEF_REGISTRY"
+
+// registryFile is the on-disk format of a registry file
+type registryFile struct {
+	Tools map[string]toolSpec `toml:"tools"`
+}
+
+// toolSpec is the declarative form of a PluginConfig
+type toolSpec struct {
+	Cmd                     string            `toml:"cmd"`
+	Repo                    string            `toml:"repo"`
+	Source                  string            `toml:"source"`
+	APIURL                  string            `toml:"api_url"`
+	WebURL                  string            `toml:"web_url"`
+	VersionsURL             string            `toml:"versions_url"`
+	VersionsPath            string            `toml:"versions_path"`
+	VersionPattern          string            `toml:"version_pattern"`
+	DownloadURL             string            `toml:"download_url"`
+	AssetTemplate           string            `toml:"asset_template"`
+	RelativeBinPathTemplate string            `toml:"relative_bin_path_template"`
+	Bins                    []binSpec         `toml:"bins"`
+	Completions             map[string]string `toml:"completions"`
+	ManPages                []string          `toml:"man_pages"`
+	StripComponents         int               `toml:"strip_components"`
+	Include                 []string          `toml:"include"`
+	Exclude                 []string          `toml:"exclude"`
+	ArchiveFormat           string            `toml:"archive_format"`
+	PlatformMap             map[string]string `toml:"platform_map"`
+	ArchMap                 map[string]string `toml:"arch_map"`
+	ReleaseFilter           *filterSpec       `toml:"release_filter"`
+	FormatVersion           []transformSpec   `toml:"format_version"`
+	RecoverVersion          []transformSpec   `toml:"recover_version"`
+	Checksum                *checksumSpec     `toml:"checksum"`
+	Signature               *signatureSpec    `toml:"signature"`
+}
+
+// binSpec exposes files of the extracted asset in bin/
+type binSpec struct {
+	Path string `toml:"path"` // Template and glob, like relative_bin_path_template
+	Name string `toml:"name"` // Optional; only valid when path matches a single file
+}
+
+// signatureSpec declares how assets are signed
+type signatureSpec struct {
+	Type                      string `toml:"type"`
+	AssetTemplate             string `toml:"asset_template"`
+	PublicKey                 string `toml:"public_key"`
+	TrustedRoot               string `toml:"trusted_root"`
+	CertificateIdentity       string `toml:"certificate_identity"`
+	CertificateIdentityRegexp string `toml:"certificate_identity_regexp"`
+	CertificateOIDCIssuer     string `toml:"certificate_oidc_issuer"`
+}
+
+// checksumSpec declares a checksum asset
+type checksumSpec struct {
+	AssetTemplate string `toml:"asset_template"`
+	Format        string `toml:"format"`
+}
+
+// filterSpec selects which releases are considered. All set conditions must hold.
+type filterSpec struct {
+	TagPrefix         string `toml:"tag_prefix"`
+	TagPattern        string `toml:"tag_pattern"`
+	ExcludePrerelease bool   `toml:"exclude_prerelease"`
+}
+
+// transformSpec is a single version rewrite step.
+// Exactly one of StripPrefix, AddPrefix or Replace must be set.
+type transformSpec struct {
+	Match       string `toml:"match"` // Optional regex; the step is skipped if it does not match
+	StripPrefix string `toml:"strip_prefix"`
+	AddPrefix   string `toml:"add_prefix"` // Not added again if already present
+	Replace     string `toml:"replace"`    // Regex, replaced by With
+	With        string `toml:"with"`
+}
+
+// Load returns the built-in registry merged with the user registry file, if any.
+// A tool defined in the user file replaces the built-in tool of the same name.
+func Load() (map[string]*PluginConfig, error) {
+	registry, err := parseRegistry(defaultRegistry, "built-in registry")
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := UserRegistryPath()
+	if err != nil {
+		// No home directory, as in minimal containers: only the built-in tools
+		return registry, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && os.Getenv(UserRegistryEnv) == "" {
+		return registry, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry: %w", err)
+	}
+
+	user, err := parseRegistry(data, path)
+	if err != nil {
+		return nil, err
+	}
+	for name, plugin := range user {
+		registry[name] = plugin
+	}
+	return registry, nil
+}
+
+// UserRegistryPath returns the path of the user registry file
+func UserRegistryPath() (string, error) {
+	if path := os.Getenv(UserRegistryEnv); path != "" {
+		return path, nil
+	}
+	dir, err := util.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "registry.toml"), nil
+}
+
+func parseRegistry(data []byte, source string) (map[string]*PluginConfig, error) {
+	var file registryFile
+	md, err := toml.Decode(string(data), &file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%s: unknown key %q", source, undecoded[0].String())
+	}
+
+	registry := make(map[string]*PluginConfig, len(file.Tools))
+	for name, spec := range file.Tools {
+		plugin, err := spec.compile(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: tool %q: %w", source, name, err)
+		}
+		registry[name] = plugin
+	}
+	return registry, nil
+}
+
+func (s toolSpec) compile(name string) (*PluginConfig, error) {
+	if s.Repo == "" && s.Source != string(source.TypeURL) {
+		return nil, errors.New("repo is required")
+	}
+	if s.AssetTemplate == "" {
+		return nil, errors.New("asset_template is required")
+	}
+
+	var err error
+	p := &PluginConfig{
+		Name:                    name,
+		Cmd:                     s.Cmd,
+		Repo:                    s.Repo,
+		AssetTemplate:           s.AssetTemplate,
+		RelativeBinPathTemplate: s.RelativeBinPathTemplate,
+		StripComponents:         s.StripComponents,
+		Include:                 s.Include,
+		Exclude:                 s.Exclude,
+		ArchiveFormat:           util.Format(s.ArchiveFormat),
+	}
+	if p.Cmd == "" {
+		p.Cmd = name
+	}
+	if p.Repo == "" {
+		// Only url sources; the repo still names the tool's cache entries
+		p.Repo = name
+	}
+	if p.Source, err = s.sourceConfig(); err != nil {
+		return nil, err
+	}
+	if p.RelativeBinPathTemplate == "" {
+		p.RelativeBinPathTemplate = p.Cmd
+	}
+	if p.Bins, err = compileBins(s.Bins); err != nil {
+		return nil, fmt.Errorf("bins: %w", err)
+	}
+	if p.Bins == nil {
+		p.Bins = []BinConfig{{PathTemplate: p.RelativeBinPathTemplate, Name: p.Cmd}}
+	}
+	if p.Completions, err = compileCompletions(s.Completions); err != nil {
+		return nil, fmt.Errorf("completions: %w", err)
+	}
+	for i, page := range s.ManPages {
+		if err := checkPathTemplate(page); err != nil {
+			return nil, fmt.Errorf("man_pages: entry %d: %w", i+1, err)
+		}
+	}
+	p.ManPages = s.ManPages
+	if p.ArchiveFormat != "" {
+		if _, ok := util.ExtractorFor(p.ArchiveFormat); !ok {
+			return nil, fmt.Errorf("archive_format: unknown format %q", s.ArchiveFormat)
+		}
+	}
+	if err := util.CheckPatterns(p.Include); err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+	if err := util.CheckPatterns(p.Exclude); err != nil {
+		return nil, fmt.Errorf("exclude: %w", err)
+	}
+
+	if len(s.PlatformMap) > 0 {
+		p.PlatformMap = make(map[util.Platform]string, len(s.PlatformMap))
+		for k, v := range s.PlatformMap {
+			plat := util.Platform(k)
+			if plat != util.Darwin && plat != util.Linux {
+				return nil, fmt.Errorf("platform_map: unknown platform %q", k)
+			}
+			p.PlatformMap[plat] = v
+		}
+	}
+	if len(s.ArchMap) > 0 {
+		p.ArchMap = make(map[util.Arch]string, len(s.ArchMap))
+		for k, v := range s.ArchMap {
+			arch := util.Arch(k)
+			if arch != util.X86_64 && arch != util.Aarch64 {
+				return nil, fmt.Errorf("arch_map: unknown arch %q", k)
+			}
+			p.ArchMap[arch] = v
+		}
+	}
+
+	if s.Checksum != nil {
+		if p.Checksum, err = s.Checksum.compile(); err != nil {
+			return nil, fmt.Errorf("checksum: %w", err)
+		}
+	}
+
+	if s.Signature != nil {
+		if p.Signature, err = s.Signature.compile(); err != nil {
+			return nil, fmt.Errorf("signature: %w", err)
+		}
+		if p.Signature.Type == SignatureGitHubAttestation && p.Source.Type != source.TypeGitHub {
+			return nil, errors.New("signature: github-attestation requires a GitHub source")
+		}
+	}
+
+	if s.ReleaseFilter != nil {
+		if p.ReleaseFilter, err = s.ReleaseFilter.compile(); err != nil {
+			return nil, fmt.Errorf("release_filter: %w", err)
+		}
+	}
+	if p.FormatVersion, err = compileTransforms(s.FormatVersion); err != nil {
+		return nil, fmt.Errorf("format_version: %w", err)
+	}
+	if p.RecoverVersion, err = compileTransforms(s.RecoverVersion); err != nil {
+		return nil, fmt.Errorf("recover_version: %w", err)
+	}
+	return p, nil
+}
+
+// compileBins checks bin entries. Names must be plain file names, unique
+// within the tool.
+func compileBins(specs []binSpec) ([]BinConfig, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	bins := make([]BinConfig, 0, len(specs))
+	names := make(map[string]bool, len(specs))
+	for i, spec := range specs {
+		if spec.Path == "" {
+			return nil, fmt.Errorf("entry %d: path is required", i+1)
+		}
+		if err := checkPathTemplate(spec.Path); err != nil {
+			return nil, fmt.Errorf("entry %d: path: %w", i+1, err)
+		}
+		if spec.Name != "" {
+			if spec.Name == "." || spec.Name == ".." || strings.ContainsAny(spec.Name, `/\`) {
+				return nil, fmt.Errorf("entry %d: name %q is not a file name", i+1, spec.Name)
+			}
+			if names[spec.Name] {
+				return nil, fmt.Errorf("entry %d: duplicate name %q", i+1, spec.Name)
+			}
+			names[spec.Name] = true
+		}
+		bins = append(bins, BinConfig{PathTemplate: spec.Path, Name: spec.Name})
+	}
+	return bins, nil
+}
+
+// compileCompletions checks completion paths, keyed by shell
+func compileCompletions(specs map[string]string) (map[Shell]string, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	completions := make(map[Shell]string, len(specs))
+	for k, v := range specs {
+		shell := Shell(k)
+		if shell != ShellBash && shell != ShellZsh && shell != ShellFish {
+			return nil, fmt.Errorf("unknown shell %q", k)
+		}
+		if err := checkPathTemplate(v); err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		completions[shell] = v
+	}
+	return completions, nil
+}
+
+// checkPathTemplate reports a malformed template, or a malformed glob in a
+// path without template actions; other globs are only known once rendered
+func checkPathTemplate(p string) error {
+	if _, err := template.New("path").Parse(p); err != nil {
+		return err
+	}
+	if !strings.Contains(p, "{{") {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("%q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// sourceConfig resolves source, api_url and web_url. forgejo is an alias of
+// gitea, and codeberg is gitea on codeberg.org.
+func (s toolSpec) sourceConfig() (source.Config, error) {
+	for key, v := range map[string]string{"api_url": s.APIURL, "web_url": s.WebURL} {
+		if err := checkBaseURL(v); err != nil {
+			return source.Config{}, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	cfg := source.Config{APIBaseURL: s.APIURL, WebBaseURL: s.WebURL}
+	switch s.Source {
+	case "", "github":
+		cfg.Type = source.TypeGitHub
+	case "gitlab":
+		cfg.Type = source.TypeGitLab
+	case "gitea", "forgejo":
+		cfg.Type = source.TypeGitea
+		if cfg.APIBaseURL == "" && cfg.WebBaseURL == "" {
+			return source.Config{}, fmt.Errorf("source %q requires web_url or api_url", s.Source)
+		}
+	case "codeberg":
+		cfg.Type = source.TypeGitea
+		if cfg.WebBaseURL == "" {
+			cfg.WebBaseURL = source.DefaultCodebergURL
+		}
+	case "url":
+		cfg.Type = source.TypeURL
+		cfg.VersionsURL = s.VersionsURL
+		cfg.VersionsPath = s.VersionsPath
+		cfg.VersionPattern = s.VersionPattern
+		cfg.DownloadURL = s.DownloadURL
+		if cfg.VersionsURL == "" || cfg.DownloadURL == "" {
+			return source.Config{}, errors.New(`source "url" requires versions_url and download_url`)
+		}
+		if err := checkBaseURL(cfg.VersionsURL); err != nil {
+			return source.Config{}, fmt.Errorf("versions_url: %w", err)
+		}
+		if _, err := regexp.Compile(cfg.VersionPattern); err != nil {
+			return source.Config{}, fmt.Errorf("version_pattern: %w", err)
+		}
+	default:
+		return source.Config{}, fmt.Errorf("unknown source %q", s.Source)
+	}
+	if cfg.Type != source.TypeURL && s.VersionsURL+s.VersionsPath+s.VersionPattern+s.DownloadURL != "" {
+		return source.Config{}, errors.New(`versions_url, versions_path, version_pattern and download_url require source = "url"`)
+	}
+	return cfg, nil
+}
+
+// checkBaseURL rejects base URLs that are not absolute http(s) URLs
+func checkBaseURL(v string) error {
+	if v == "" {
+		return nil
+	}
+	u, err := url.Parse(v)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("%q is not an http(s) URL", v)
+	}
+	return nil
+}
+
+func (c checksumSpec) compile() (*ChecksumConfig, error) {
+	if c.AssetTemplate == "" {
+		return nil, errors.New("asset_template is required")
+	}
+
+	format := ChecksumFormat(c.Format)
+	switch format {
+	case "":
+		format = ChecksumGNU
+	case ChecksumGNU, ChecksumBSD, ChecksumSingle:
+	default:
+		return nil, fmt.Errorf("unknown format %q", c.Format)
+	}
+
+	return &ChecksumConfig{AssetTemplate: c.AssetTemplate, Format: format}, nil
+}
+
+func (s signatureSpec) compile() (*SignatureConfig, error) {
+	c := &SignatureConfig{
+		Type:                      SignatureType(s.Type),
+		AssetTemplate:             s.AssetTemplate,
+		PublicKey:                 s.PublicKey,
+		TrustedRoot:               s.TrustedRoot,
+		CertificateIdentity:       s.CertificateIdentity,
+		CertificateIdentityRegexp: s.CertificateIdentityRegexp,
+		CertificateOIDCIssuer:     s.CertificateOIDCIssuer,
+	}
+
+	if c.CertificateIdentityRegexp != "" {
+		if _, err := regexp.Compile(c.CertificateIdentityRegexp); err != nil {
+			return nil, fmt.Errorf("certificate_identity_regexp: %w", err)
+		}
+	}
+	keyless := c.CertificateIdentity != "" || c.CertificateIdentityRegexp != ""
+
+	switch c.Type {
+	case SignatureMinisign:
+		if c.AssetTemplate == "" {
+			c.AssetTemplate = "{{.Asset}}.minisig"
+		}
+		if c.PublicKey == "" {
+			return nil, errors.New("minisign requires public_key")
+		}
+	case SignatureCosign:
+		if c.AssetTemplate == "" {
+			c.AssetTemplate = "{{.Asset}}.sig"
+		}
+		if c.PublicKey == "" && !keyless {
+			return nil, errors.New("cosign requires public_key or a certificate identity")
+		}
+	case SignatureGitHubAttestation:
+		if c.PublicKey == "" && !keyless {
+			return nil, errors.New("github-attestation requires a certificate identity")
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %q", s.Type)
+	}
+	return c, nil
+}
+
+func (f filterSpec) compile() (func(source.Release) bool, error) {
+	var pattern *regexp.Regexp
+	if f.TagPattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(f.TagPattern); err != nil {
+			return nil, fmt.Errorf("tag_pattern: %w", err)
+		}
+	}
+
+	return func(r source.Release) bool {
+		if f.ExcludePrerelease && r.Prerelease {
+			return false
+		}
+		if f.TagPrefix != "" && !strings.HasPrefix(r.TagName, f.TagPrefix) {
+			return false
+		}
+		if pattern != nil && !pattern.MatchString(r.TagName) {
+			return false
+		}
+		return true
+	}, nil
+}
+
+// compileTransforms turns a list of steps into a single function.
+// An empty list yields nil, which callers treat as the identity.
+func compileTransforms(specs []transformSpec) (func(string) string, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	steps := make([]func(string) string, 0, len(specs))
+	for i, spec := range specs {
+		step, err := spec.compile()
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+		steps = append(steps, step)
+	}
+
+	return func(v string) string {
+		for _, step := range steps {
+			v = step(v)
+		}
+		return v
+	}, nil
+}
+
+func (t transformSpec) compile() (func(string) string, error) {
+	set := 0
+	for _, s := range []string{t.StripPrefix, t.AddPrefix, t.Replace} {
+		if s != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, errors.New("exactly one of strip_prefix, add_prefix or replace must be set")
+	}
+
+	var match *regexp.Regexp
+	if t.Match != "" {
+		var err error
+		if match, err = regexp.Compile(t.Match); err != nil {
+			return nil, fmt.Errorf("match: %w", err)
+		}
+	}
+
+	var apply func(string) string
+	switch {
+	case t.StripPrefix != "":
+		apply = func(v string) string {
+			return strings.TrimPrefix(v, t.StripPrefix)
+		}
+	case t.AddPrefix != "":
+		apply = func(v string) string {
+			if strings.HasPrefix(v, t.AddPrefix) {
+				return v
+			}
+			return t.AddPrefix + v
+		}
+	default:
+		re, err := regexp.Compile(t.Replace)
+		if err != nil {
+			return nil, fmt.Errorf("replace: %w", err)
+		}
+		apply = func(v string) string {
+			return re.ReplaceAllString(v, t.With)
+		}
+	}
+
+	if match == nil {
+		return apply, nil
+	}
+	return func(v string) string {
+		if !match.MatchString(v) {
+			return v
+		}
+		return apply(v)
+	}, nil
+}
@@ -0,0 +1,45 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadUserRegistry(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "registry.toml")
+	if err := os.WriteFile(valid, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{"missing default file", map[string]string{"XDG_CONFIG_HOME": dir}, false},
+		{"explicit file", map[string]string{UserRegistryEnv: valid}, false},
+		{"missing explicit file", map[string]string{UserRegistryEnv: filepath.Join(dir, "missing.toml")}, true},
+		// Without a home directory only the built-in registry is loaded
+		{"no home", map[string]string{"HOME": ""}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(UserRegistryEnv, "")
+			t.Setenv("XDG_CONFIG_HOME", "")
+			t.Setenv("HOME", dir)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			registry, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(registry) == 0 {
+				t.Error("Load() returned an empty registry")
+			}
+		})
+	}
+}
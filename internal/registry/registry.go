@@ -2,11 +2,10 @@ package registry
 
 import (
 	"sort"
-	"strings"
 
 	"golang.org/x/mod/semver"
 
-	"github.com/aniaan/sous-chef/internal/gh"
+	"github.com/aniaan/sous-chef/internal/source"
 	"github.com/aniaan/sous-chef/internal/util"
 )
 
@@ -15,40 +14,107 @@ type PluginConfig struct {
 	Name                    string
 	Cmd                     string
 	Repo                    string
-	AssetTemplate           string // Go template format: bat-v{{.Version}}-{{.Arch}}-{{.Platform}}.tar.gz
-	RelativeBinPathTemplate string // Relative path to binary AFTER extraction (and stripping)
-	StripComponents         int    // Number of leading directories to strip when extracting
-	ReleaseFilter           func(gh.Release) bool
+	Source                  source.Config    // Forge hosting Repo (default: the client's GitHub instance)
+	AssetTemplate           string           // Go template format: bat-v{{.Version}}-{{.Arch}}-{{.Platform}}.tar.gz
+	RelativeBinPathTemplate string           // Relative path to binary AFTER extraction (and stripping)
+	Bins                    []BinConfig      // Files exposed in bin/ (default: Cmd at RelativeBinPathTemplate)
+	Completions             map[Shell]string // Optional templates and globs of completion scripts, per shell
+	ManPages                []string         // Optional templates and globs of man pages
+	StripComponents         int              // Number of leading directories to strip when extracting
+	Include                 []string         // Optional path.Match patterns of archive entries to extract
+	Exclude                 []string         // Optional path.Match patterns of archive entries to skip
+	ArchiveFormat           util.Format      // Overrides detection for assets with unusual names
+	ReleaseFilter           func(source.Release) bool
 	PlatformMap             map[util.Platform]string
 	ArchMap                 map[util.Arch]string
 	FormatVersion           func(string) string // GitHub Tag -> Display Version
 	RecoverVersion          func(string) string // Display Version -> GitHub Tag
+	Checksum                *ChecksumConfig     // Optional checksum asset, used when GitHub has no digest
+	Signature               *SignatureConfig    // Optional signature verified before extraction
 }
 
-// GetReleases fetches, filters, and sorts releases for the plugin
-func (p *PluginConfig) GetReleases(client *gh.Client) ([]gh.Release, error) {
-	releases, err := client.ListReleases(p.Repo)
+// BinConfig exposes files of the extracted asset in bin/
+type BinConfig struct {
+	PathTemplate string // Go template, then a filepath.Match glob relative to the extracted asset
+	Name         string // Name in bin/; defaults to the base name of each matched file
+}
+
+// Shell is a shell that completion scripts are installed for
+type Shell string
+
+const (
+	ShellBash Shell = "bash" // share/bash-completion/completions/<cmd>
+	ShellZsh  Shell = "zsh"  // share/zsh/site-functions/_<cmd>
+	ShellFish Shell = "fish" // share/fish/vendor_completions.d/<cmd>.fish
+)
+
+// Shells lists the supported shells in a stable order
+var Shells = []Shell{ShellBash, ShellZsh, ShellFish}
+
+// ChecksumFormat is the layout of a checksum asset
+type ChecksumFormat string
+
+const (
+	ChecksumGNU    ChecksumFormat = "gnu"    // "<hash>  <filename>" lines, as written by sha256sum
+	ChecksumBSD    ChecksumFormat = "bsd"    // "SHA256 (<filename>) = <hash>" lines
+	ChecksumSingle ChecksumFormat = "single" // Only the hash of one asset, e.g. <asset>.sha256
+)
+
+// ChecksumConfig describes a SHA-256 checksum asset published with each release
+type ChecksumConfig struct {
+	AssetTemplate string // Go template; .Asset is the rendered asset filename
+	Format        ChecksumFormat
+}
+
+// SignatureType is the signing scheme used for release assets
+type SignatureType string
+
+const (
+	SignatureMinisign          SignatureType = "minisign"           // <asset>.minisig with a minisign public key
+	SignatureCosign            SignatureType = "cosign"             // cosign signature or Sigstore bundle asset
+	SignatureGitHubAttestation SignatureType = "github-attestation" // GitHub artifact attestation (Sigstore bundle)
+)
+
+// SignatureConfig declares how to verify a release asset's signature.
+// Keyed schemes set PublicKey; keyless schemes set an identity.
+type SignatureConfig struct {
+	Type                      SignatureType
+	AssetTemplate             string // Go template; .Asset is the rendered asset filename
+	PublicKey                 string // minisign base64 key, or PEM public key for cosign
+	TrustedRoot               string // Sigstore trusted_root.json; defaults to the public-good instance
+	CertificateIdentity       string
+	CertificateIdentityRegexp string
+	CertificateOIDCIssuer     string
+}
+
+// GetReleases fetches, filters, and sorts releases for the plugin.
+// If limit > 0, pagination stops once at least limit releases pass the filter;
+// otherwise every page up to the client's page cap is fetched.
+func (p *PluginConfig) GetReleases(src source.Source, limit int) ([]source.Release, error) {
+	var more func([]source.Release) bool
+	if limit > 0 {
+		more = func(releases []source.Release) bool {
+			return len(p.filterReleases(releases)) < limit
+		}
+	}
+	return p.listReleases(src, more)
+}
+
+// listReleases lists releases until more returns false, filtered and sorted
+// like GetReleases
+func (p *PluginConfig) listReleases(src source.Source, more func([]source.Release) bool) ([]source.Release, error) {
+	releases, err := src.ListReleases(p.Repo, more)
 	if err != nil {
 		return nil, err
 	}
 
-	// Filter releases
-	var filtered []gh.Release
-	for _, r := range releases {
-		if p.ReleaseFilter != nil {
-			if p.ReleaseFilter(r) {
-				filtered = append(filtered, r)
-			}
-		} else {
-			filtered = append(filtered, r)
-		}
-	}
+	filtered := p.filterReleases(releases)
 
 	// Sort by semantic version (descending)
 	// Invalid semver versions are sorted to the end
 	sort.Slice(filtered, func(i, j int) bool {
-		vi := "v" + p.FormatVersion(filtered[i].TagName)
-		vj := "v" + p.FormatVersion(filtered[j].TagName)
+		vi := "v" + p.GetDisplayVersion(filtered[i].TagName)
+		vj := "v" + p.GetDisplayVersion(filtered[j].TagName)
 		viValid := semver.IsValid(vi)
 		vjValid := semver.IsValid(vj)
 		if viValid && vjValid {
@@ -65,6 +131,21 @@ func (p *PluginConfig) GetReleases(client *gh.Client) ([]gh.Release, error) {
 	return filtered, nil
 }
 
+// filterReleases returns the releases accepted by ReleaseFilter
+func (p *PluginConfig) filterReleases(releases []source.Release) []source.Release {
+	if p.ReleaseFilter == nil {
+		return releases
+	}
+
+	var filtered []source.Release
+	for _, r := range releases {
+		if p.ReleaseFilter(r) {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
 // GetDisplayVersion converts a GitHub tag to a user-friendly version string
 func (p *PluginConfig) GetDisplayVersion(tag string) string {
 	if p.FormatVersion != nil {
@@ -72,333 +153,3 @@ func (p *PluginConfig) GetDisplayVersion(tag string) string {
 	}
 	return tag
 }
-
-// Registry stores all known plugins
-var Registry = map[string]*PluginConfig{
-	"neovim": {
-		Name:                    "neovim",
-		Cmd:                     "nvim",
-		Repo:                    "neovim/neovim",
-		AssetTemplate:           "nvim-{{.Platform}}-{{.Arch}}.tar.gz",
-		RelativeBinPathTemplate: "bin/nvim",
-		StripComponents:         1,
-		PlatformMap: map[util.Platform]string{
-			util.Darwin: "macos",
-			util.Linux:  "linux",
-		},
-		ArchMap: map[util.Arch]string{
-			util.X86_64:  "x86_64",
-			util.Aarch64: "arm64",
-		},
-		FormatVersion:  RemoveVPrefixFormatVersion,
-		RecoverVersion: AddVPrefixRecoverVersion,
-	},
-	"rust-analyzer": {
-		Name:                    "rust-analyzer",
-		Cmd:                     "rust-analyzer",
-		Repo:                    "rust-lang/rust-analyzer",
-		AssetTemplate:           "rust-analyzer-{{.Arch}}-{{.Platform}}.gz",
-		RelativeBinPathTemplate: "rust-analyzer",
-		StripComponents:         0,
-		PlatformMap: map[util.Platform]string{
-			util.Darwin: "apple-darwin",
-			util.Linux:  "unknown-linux-gnu",
-		},
-		FormatVersion: func(v string) string {
-			return strings.ReplaceAll(v, "-", ".")
-		},
-		RecoverVersion: func(v string) string {
-			return strings.ReplaceAll(v, ".", "-")
-		},
-	},
-	"lazygit": {
-		Name:                    "lazygit",
-		Cmd:                     "lazygit",
-		Repo:                    "jesseduffield/lazygit",
-		AssetTemplate:           "lazygit_{{.Version}}_{{.Platform}}_{{.Arch}}.tar.gz",
-		RelativeBinPathTemplate: "lazygit",
-		StripComponents:         0,
-		PlatformMap: map[util.Platform]string{
-			util.Darwin: "darwin",
-			util.Linux:  "linux",
-		},
-		ArchMap: map[util.Arch]string{
-			util.X86_64:  "x86_64",
-			util.Aarch64: "arm64",
-		},
-		FormatVersion:  RemoveVPrefixFormatVersion,
-		RecoverVersion: AddVPrefixRecoverVersion,
-	},
-	"fzf": {
-		Name:                    "fzf",
-		Cmd:                     "fzf",
-		Repo:                    "junegunn/fzf",
-		AssetTemplate:           "fzf-{{.Version}}-{{.Platform}}_{{.Arch}}.tar.gz",
-		RelativeBinPathTemplate: "fzf",
-		StripComponents:         0,
-		PlatformMap: map[util.Platform]string{
-			util.Darwin: "darwin",
-			util.Linux:  "linux",
-		},
-		ArchMap: map[util.Arch]string{
-			util.X86_64:  "amd64",
-			util.Aarch64: "arm64",
-		},
-		FormatVersion:  RemoveVPrefixFormatVersion,
-		RecoverVersion: AddVPrefixRecoverVersion,
-	},
-	"fd": {
-		Name:                    "fd",
-		Cmd:                     "fd",
-		Repo:                    "sharkdp/fd",
-		AssetTemplate:           "fd-v{{.Version}}-{{.Arch}}-{{.Platform}}.tar.gz",
-		RelativeBinPathTemplate: "fd",
-		StripComponents:         1,
-		PlatformMap: map[util.Platform]string{
-			util.Darwin: "apple-darwin",
-			util.Linux:  "unknown-linux-gnu",
-		},
-		FormatVersion:  RemoveVPrefixFormatVersion,
-		RecoverVersion: AddVPrefixRecoverVersion,
-	},
-	"ripgrep": {
-		Name:                    "ripgrep",
-		Cmd:                     "rg",
-		Repo:                    "BurntSushi/ripgrep",
-		AssetTemplate:           "ripgrep-{{.Version}}-{{.Arch}}-{{.Platform}}.tar.gz",
-		RelativeBinPathTemplate: "rg",
-		StripComponents:         1,
-		PlatformMap: map[util.Platform]string{
-			util.Darwin: "apple-darwin",
-			util.Linux:  "unknown-linux-musl",
-		},
-		FormatVersion:  NoOpVersion,
-		RecoverVersion: NoOpVersion,
-	},
-	"gh": {
-		Name:                    "gh",
-		Cmd:                     "gh",
-		Repo:                    "cli/cli",
-		AssetTemplate:           "gh_{{.Version}}_{{.Platform}}_{{.Arch}}.{{if eq .Platform \"macOS\"}}zip{{else}}tar.gz{{end}}",
-		RelativeBinPathTemplate: "bin/gh",
-		StripComponents:         1,
-		PlatformMap: map[util.Platform]string{
-			util.Darwin: "macOS",
-			util.Linux:  "linux",
-		},
-		ArchMap: map[util.Arch]string{
-			util.X86_64:  "amd64",
-			util.Aarch64: "arm64",
-		},
-		FormatVersion:  RemoveVPrefixFormatVersion,
-		RecoverVersion: AddVPrefixRecoverVersion,
-	},
-	"shfmt": {
-		Name:                    "shfmt",
-		Cmd:                     "shfmt",
-		Repo:                    "mvdan/sh",
-		AssetTemplate:           "shfmt_v{{.Version}}_{{.Platform}}_{{.Arch}}",
-		RelativeBinPathTemplate: "shfmt",
-		StripComponents:         0,
-		ArchMap: map[util.Arch]string{
-			util.X86_64:  "amd64",
-			util.Aarch64: "arm64",
-		},
-		FormatVersion:  RemoveVPrefixFormatVersion,
-		RecoverVersion: AddVPrefixRecoverVersion,
-	},
-	"gofumpt": {
-		Name:                    "gofumpt",
-		Cmd:                     "gofumpt",
-		Repo:                    "mvdan/gofumpt",
-		AssetTemplate:           "gofumpt_v{{.Version}}_{{.Platform}}_{{.Arch}}",
-		RelativeBinPathTemplate: "gofumpt",
-		StripComponents:         0,
-		ArchMap: map[util.Arch]string{
-			util.X86_64:  "amd64",
-			util.Aarch64: "arm64",
-		},
-		FormatVersion:  RemoveVPrefixFormatVersion,
-		RecoverVersion: AddVPrefixRecoverVersion,
-	},
-	"taplo": {
-		Name:                    "taplo",
-		Cmd:                     "taplo",
-		Repo:                    "tamasfe/taplo",
-		AssetTemplate:           "taplo-{{.Platform}}-{{.Arch}}.gz",
-		RelativeBinPathTemplate: "taplo",
-		StripComponents:         0,
-		ReleaseFilter: func(r gh.Release) bool {
-			// Only accept simple version tags like "0.10.0"
-			if len(r.TagName) == 0 {
-				return false
-			}
-			return r.TagName[0] >= '0' && r.TagName[0] <= '9'
-		},
-		FormatVersion:  NoOpVersion,
-		RecoverVersion: NoOpVersion,
-	},
-	"stylua": {
-		Name:                    "stylua",
-		Cmd:                     "stylua",
-		Repo:                    "JohnnyMorganz/StyLua",
-		AssetTemplate:           "stylua-{{.Platform}}-{{.Arch}}.zip",
-		RelativeBinPathTemplate: "stylua",
-		StripComponents:         0,
-		PlatformMap: map[util.Platform]string{
-			util.Darwin: "macos",
-			util.Linux:  "linux",
-		},
-		FormatVersion:  RemoveVPrefixFormatVersion,
-		RecoverVersion: AddVPrefixRecoverVersion,
-	},
-	"lua-language-server": {
-		Name:                    "lua-language-server",
-		Cmd:                     "lua-language-server",
-		Repo:                    "LuaLS/lua-language-server",
-		AssetTemplate:           "lua-language-server-{{.Version}}-{{.Platform}}-{{.Arch}}.tar.gz",
-		RelativeBinPathTemplate: "bin/lua-language-server",
-		StripComponents:         0,
-		ArchMap: map[util.Arch]string{
-			util.X86_64:  "x64",
-			util.Aarch64: "arm64",
-		},
-		FormatVersion:  NoOpVersion,
-		RecoverVersion: NoOpVersion,
-	},
-	"starship": {
-		Name:                    "starship",
-		Cmd:                     "starship",
-		Repo:                    "starship/starship",
-		AssetTemplate:           "starship-{{.Arch}}-{{.Platform}}.tar.gz",
-		RelativeBinPathTemplate: "starship",
-		StripComponents:         0,
-		PlatformMap: map[util.Platform]string{
-			util.Darwin: "apple-darwin",
-			util.Linux:  "unknown-linux-gnu",
-		},
-		FormatVersion:  RemoveVPrefixFormatVersion,
-		RecoverVersion: AddVPrefixRecoverVersion,
-	},
-	"zoxide": {
-		Name:                    "zoxide",
-		Cmd:                     "zoxide",
-		Repo:                    "ajeetdsouza/zoxide",
-		AssetTemplate:           "zoxide-{{.Version}}-{{.Arch}}-{{.Platform}}.tar.gz",
-		RelativeBinPathTemplate: "zoxide",
-		StripComponents:         0,
-		PlatformMap: map[util.Platform]string{
-			util.Darwin: "apple-darwin",
-			util.Linux:  "unknown-linux-musl",
-		},
-		FormatVersion:  RemoveVPrefixFormatVersion,
-		RecoverVersion: AddVPrefixRecoverVersion,
-	},
-	"uv": {
-		Name:                    "uv",
-		Cmd:                     "uv",
-		Repo:                    "astral-sh/uv",
-		AssetTemplate:           "uv-{{.Arch}}-{{.Platform}}.tar.gz",
-		RelativeBinPathTemplate: "uv",
-		StripComponents:         1,
-		PlatformMap: map[util.Platform]string{
-			util.Darwin: "apple-darwin",
-			util.Linux:  "unknown-linux-gnu",
-		},
-		FormatVersion:  NoOpVersion,
-		RecoverVersion: NoOpVersion,
-	},
-	"tree-sitter": {
-		Name:                    "tree-sitter",
-		Cmd:                     "tree-sitter",
-		Repo:                    "tree-sitter/tree-sitter",
-		AssetTemplate:           "tree-sitter-{{.Platform}}-{{.Arch}}.gz",
-		RelativeBinPathTemplate: "tree-sitter",
-		StripComponents:         0,
-		PlatformMap: map[util.Platform]string{
-			util.Darwin: "macos",
-			util.Linux:  "linux",
-		},
-		ArchMap: map[util.Arch]string{
-			util.X86_64:  "x64",
-			util.Aarch64: "arm64",
-		},
-		FormatVersion:  RemoveVPrefixFormatVersion,
-		RecoverVersion: AddVPrefixRecoverVersion,
-	},
-	"ty": {
-		Name:                    "ty",
-		Cmd:                     "ty",
-		Repo:                    "astral-sh/ty",
-		AssetTemplate:           "ty-{{.Arch}}-{{.Platform}}.tar.gz",
-		RelativeBinPathTemplate: "ty",
-		StripComponents:         1,
-		PlatformMap: map[util.Platform]string{
-			util.Darwin: "apple-darwin",
-			util.Linux:  "unknown-linux-gnu",
-		},
-		ReleaseFilter: func(r gh.Release) bool {
-			return !r.Prerelease
-		},
-		FormatVersion:  NoOpVersion,
-		RecoverVersion: NoOpVersion,
-	},
-	"codex": {
-		Name:                    "codex",
-		Cmd:                     "codex",
-		Repo:                    "openai/codex",
-		AssetTemplate:           "codex-{{.Arch}}-{{.Platform}}.tar.gz",
-		RelativeBinPathTemplate: "codex-{{.Arch}}-{{.Platform}}",
-		StripComponents:         0,
-		PlatformMap: map[util.Platform]string{
-			util.Darwin: "apple-darwin",
-			util.Linux:  "unknown-linux-musl",
-		},
-		ReleaseFilter: func(r gh.Release) bool {
-			return strings.HasPrefix(r.TagName, "rust-v") && !r.Prerelease
-		},
-		RecoverVersion: func(v string) string {
-			if strings.HasPrefix(v, "rust-v") {
-				return v
-			}
-			return "rust-v" + strings.TrimPrefix(v, "v")
-		},
-		FormatVersion: func(v string) string {
-			return strings.TrimPrefix(v, "rust-v")
-		},
-	},
-	"zls": {
-		Name:                    "zls",
-		Cmd:                     "zls",
-		Repo:                    "zigtools/zls",
-		AssetTemplate:           "zls-{{.Arch}}-{{.Platform}}.tar.xz",
-		RelativeBinPathTemplate: "zls",
-		StripComponents:         0,
-		ArchMap: map[util.Arch]string{
-			util.X86_64:  "x86_64",
-			util.Aarch64: "aarch64",
-		},
-		PlatformMap: map[util.Platform]string{
-			util.Darwin: "macos",
-			util.Linux:  "linux",
-		},
-		FormatVersion:  NoOpVersion,
-		RecoverVersion: NoOpVersion,
-	},
-}
-
-func NoOpVersion(v string) string {
-	return v
-}
-
-func RemoveVPrefixFormatVersion(v string) string {
-	return strings.TrimPrefix(v, "v")
-}
-
-// DefaultRecoverVersion adds 'v' prefix
-func AddVPrefixRecoverVersion(v string) string {
-	if !strings.HasPrefix(v, "v") && len(v) > 0 && v[0] >= '0' && v[0] <= '9' {
-		return "v" + v
-	}
-	return v
-}
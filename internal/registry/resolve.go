@@ -0,0 +1,371 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+
+	"github.com/aniaan/sous-chef/internal/source"
+)
+
+// Version aliases accepted by Resolve
+const (
+	AliasLatest       = "latest"        // Newest release accepted by ReleaseFilter
+	AliasLatestStable = "latest-stable" // Newest release that is not a prerelease
+	AliasPrerelease   = "prerelease"    // Newest prerelease
+)
+
+// ErrNoMatch is returned when no release satisfies a version spec
+var ErrNoMatch = errors.New("no matching release")
+
+var numberPattern = regexp.MustCompile(`^(0|[1-9][0-9]*)$`)
+
+// IsVersionSpec reports whether spec is an alias or a range rather than an
+// exact version that RecoverVersion can turn into a tag
+func IsVersionSpec(spec string) bool {
+	switch spec {
+	case AliasLatest, AliasLatestStable, AliasPrerelease:
+		return true
+	}
+	if strings.ContainsAny(spec, "^~<>=*| ,") {
+		return true
+	}
+	// Wildcards (1.x) and partial versions (1, 1.2) are ranges
+	p, err := parsePartial(spec)
+	return err == nil && len(p.nums) < 3
+}
+
+// Resolve returns the newest release whose display version satisfies spec.
+// spec is an alias, a range such as "^0.10", "~1.2", ">=0.40 <0.50" or "1.x"
+// (comparators separated by spaces or commas, alternatives by "||"), or an
+// exact display version. A release whose display version is spec itself wins
+// over the range spec names.
+func (p *PluginConfig) Resolve(src source.Source, spec string) (source.Release, error) {
+	spec = strings.TrimSpace(spec)
+
+	if spec == AliasLatest {
+		releases, err := p.GetReleases(src, 1)
+		if err != nil {
+			return source.Release{}, err
+		}
+		if len(releases) == 0 {
+			return source.Release{}, fmt.Errorf("%w: %s has no releases", ErrNoMatch, p.Name)
+		}
+		return releases[0], nil
+	}
+
+	match, err := p.matcher(spec)
+	if err != nil {
+		return source.Release{}, err
+	}
+
+	// A range needs no pages older than the lowest version it matches
+	var more func([]source.Release) bool
+	if spec != AliasLatestStable && spec != AliasPrerelease && IsVersionSpec(spec) {
+		if c, err := ParseConstraint(spec); err == nil {
+			if floor, ok := c.Floor(); ok {
+				more = p.pagesDownTo(floor)
+			}
+		}
+	}
+	releases, err := p.listReleases(src, more)
+	if err != nil {
+		return source.Release{}, err
+	}
+	// Some tools really tag partial versions such as v1.2, which must not
+	// resolve to the newest 1.2.x
+	if spec != AliasLatestStable && spec != AliasPrerelease {
+		for _, r := range releases {
+			if p.GetDisplayVersion(r.TagName) == spec {
+				return r, nil
+			}
+		}
+	}
+	// Releases are sorted newest first
+	for _, r := range releases {
+		if match(r) {
+			return r, nil
+		}
+	}
+	return source.Release{}, fmt.Errorf("%w: no release of %s satisfies %q", ErrNoMatch, p.Name, spec)
+}
+
+// matcher compiles spec into a release predicate
+func (p *PluginConfig) matcher(spec string) (func(source.Release) bool, error) {
+	switch spec {
+	case AliasLatestStable:
+		return func(r source.Release) bool { return !p.isPrerelease(r) }, nil
+	case AliasPrerelease:
+		return func(r source.Release) bool { return p.isPrerelease(r) }, nil
+	}
+
+	if !IsVersionSpec(spec) {
+		return func(r source.Release) bool { return p.GetDisplayVersion(r.TagName) == spec }, nil
+	}
+
+	c, err := ParseConstraint(spec)
+	if err != nil {
+		return nil, err
+	}
+	return func(r source.Release) bool {
+		v := "v" + p.GetDisplayVersion(r.TagName)
+		if !semver.IsValid(v) {
+			return false
+		}
+		if p.isPrerelease(r) && !c.prerelease {
+			return false
+		}
+		return c.Match(v)
+	}, nil
+}
+
+// pagesDownTo returns a ListReleases callback that stops paging once a page
+// ends with a release below floor. Releases are listed newest first, so
+// later pages only hold older versions, barring late backports.
+func (p *PluginConfig) pagesDownTo(floor string) func([]source.Release) bool {
+	seen := 0
+	return func(releases []source.Release) bool {
+		page := p.filterReleases(releases[seen:])
+		seen = len(releases)
+		for i := len(page) - 1; i >= 0; i-- {
+			if v := "v" + p.GetDisplayVersion(page[i].TagName); semver.IsValid(v) {
+				return semver.Compare(v, floor) >= 0
+			}
+		}
+		return true
+	}
+}
+
+// isPrerelease reports whether a release is marked as a prerelease by its
+// forge or carries a semver prerelease suffix
+func (p *PluginConfig) isPrerelease(r source.Release) bool {
+	return r.Prerelease || semver.Prerelease("v"+p.GetDisplayVersion(r.TagName)) != ""
+}
+
+// comparator is a single bound on a canonical semver version
+type comparator struct {
+	op      string // ">=", ">", "<=", "<" or "="
+	version string // Canonical, with a leading "v"
+}
+
+func (c comparator) match(v string) bool {
+	cmp := semver.Compare(v, c.version)
+	switch c.op {
+	case ">=":
+		return cmp >= 0
+	case ">":
+		return cmp > 0
+	case "<=":
+		return cmp <= 0
+	case "<":
+		return cmp < 0
+	}
+	return cmp == 0
+}
+
+// Constraint is a parsed version range: any of its alternatives must hold,
+// where an alternative holds when all of its comparators do
+type Constraint struct {
+	alternatives [][]comparator
+	prerelease   bool // Whether prereleases may match, i.e. a bound names one
+}
+
+// Match reports whether a semver version (with a leading "v") satisfies c
+func (c Constraint) Match(v string) bool {
+	for _, alt := range c.alternatives {
+		ok := true
+		for _, cmp := range alt {
+			if !cmp.match(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Floor returns the lowest version c can match, and false if an alternative
+// has no lower bound
+func (c Constraint) Floor() (string, bool) {
+	floor := ""
+	for _, alt := range c.alternatives {
+		lower := ""
+		for _, cmp := range alt {
+			if cmp.op != "<" && cmp.op != "<=" && (lower == "" || semver.Compare(cmp.version, lower) > 0) {
+				lower = cmp.version
+			}
+		}
+		if lower == "" {
+			return "", false
+		}
+		if floor == "" || semver.Compare(lower, floor) < 0 {
+			floor = lower
+		}
+	}
+	return floor, floor != ""
+}
+
+// ParseConstraint parses a version range such as "^0.10", "~1.2", "1.x" or
+// ">=0.40 <0.50". Prereleases only match when a bound names a prerelease.
+func ParseConstraint(spec string) (Constraint, error) {
+	var c Constraint
+	for _, alt := range strings.Split(spec, "||") {
+		var cmps []comparator
+		for _, term := range splitTerms(alt) {
+			parsed, err := parseTerm(term)
+			if err != nil {
+				return Constraint{}, fmt.Errorf("invalid version constraint %q: %w", spec, err)
+			}
+			cmps = append(cmps, parsed...)
+			if strings.Contains(term, "-") {
+				c.prerelease = true
+			}
+		}
+		if len(cmps) == 0 {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q: empty range", spec)
+		}
+		c.alternatives = append(c.alternatives, cmps)
+	}
+	return c, nil
+}
+
+// splitTerms splits a range on spaces and commas, keeping an operator
+// separated from its version (">= 1.2") as one term
+func splitTerms(s string) []string {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' })
+	var terms []string
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if strings.Trim(f, "<>=^~") == "" && i+1 < len(fields) {
+			f += fields[i+1]
+			i++
+		}
+		terms = append(terms, f)
+	}
+	return terms
+}
+
+// parseTerm expands one range term into comparators
+func parseTerm(term string) ([]comparator, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if rest, ok := strings.CutPrefix(term, prefix); ok {
+			op, term = prefix, rest
+			break
+		}
+	}
+
+	p, err := parsePartial(term)
+	if err != nil {
+		return nil, err
+	}
+	n := len(p.nums)
+	lower := p.version()
+	if n == 0 {
+		// "*", "^x" and the like match everything
+		return []comparator{{">=", lower}}, nil
+	}
+
+	switch op {
+	case "^":
+		// Bump the first non-zero component, or the last given one
+		i := 0
+		for i < n-1 && p.nums[i] == 0 {
+			i++
+		}
+		return []comparator{{">=", lower}, {"<", p.bump(i)}}, nil
+	case "~":
+		// ~1 allows minor updates, ~1.2 and ~1.2.3 only patch updates
+		return []comparator{{">=", lower}, {"<", p.bump(min(n-1, 1))}}, nil
+	case ">=":
+		return []comparator{{">=", lower}}, nil
+	case "<":
+		return []comparator{{"<", lower}}, nil
+	case ">":
+		if n < 3 {
+			return []comparator{{">=", p.bump(n - 1)}}, nil
+		}
+		return []comparator{{">", lower}}, nil
+	case "<=":
+		if n < 3 {
+			return []comparator{{"<", p.bump(n - 1)}}, nil
+		}
+		return []comparator{{"<=", lower}}, nil
+	}
+
+	// Exact, wildcard or partial version
+	if n == 3 {
+		return []comparator{{"=", lower}}, nil
+	}
+	return []comparator{{">=", lower}, {"<", p.bump(n - 1)}}, nil
+}
+
+// partialVersion is a version with up to three numeric components, where
+// missing or wildcard components are unspecified
+type partialVersion struct {
+	nums []int
+	pre  string
+}
+
+// parsePartial parses "1", "1.2", "1.2.3", "1.x", "1.2.*" or "1.2.3-rc.1",
+// with an optional leading "v"
+func parsePartial(s string) (partialVersion, error) {
+	var p partialVersion
+	s = strings.TrimPrefix(s, "v")
+	s, _, _ = strings.Cut(s, "+")
+	s, p.pre, _ = strings.Cut(s, "-")
+	if s == "" {
+		return p, errors.New("missing version")
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return p, fmt.Errorf("too many components in %q", s)
+	}
+	wildcard := false
+	for _, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			wildcard = true
+			continue
+		}
+		if wildcard || !numberPattern.MatchString(part) {
+			return p, fmt.Errorf("invalid version %q", s)
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return p, err
+		}
+		p.nums = append(p.nums, n)
+	}
+	if p.pre != "" && len(p.nums) < 3 {
+		return p, fmt.Errorf("prerelease needs a full version: %q", s)
+	}
+	return p, nil
+}
+
+// version returns the lowest canonical version matching p
+func (p partialVersion) version() string {
+	var nums [3]int
+	copy(nums[:], p.nums)
+	v := fmt.Sprintf("v%d.%d.%d", nums[0], nums[1], nums[2])
+	if p.pre != "" {
+		v += "-" + p.pre
+	}
+	return v
+}
+
+// bump returns the first version after every version sharing p's
+// components up to and including index i
+func (p partialVersion) bump(i int) string {
+	var nums [3]int
+	copy(nums[:], p.nums[:i+1])
+	nums[i]++
+	return fmt.Sprintf("v%d.%d.%d", nums[0], nums[1], nums[2])
+}
@@ -0,0 +1,255 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aniaan/sous-chef/internal/auth"
+	"github.com/aniaan/sous-chef/internal/source"
+)
+
+// fakeSource serves a fixed list of releases
+type fakeSource struct {
+	releases []source.Release
+}
+
+func (s fakeSource) Host() string                        { return "example.com" }
+func (s fakeSource) Credential() (auth.Credential, bool) { return auth.Credential{}, false }
+
+func (s fakeSource) ListReleases(string, func([]source.Release) bool) ([]source.Release, error) {
+	return s.releases, nil
+}
+
+func (s fakeSource) GetRelease(_, tag string) (*source.Release, error) {
+	for _, r := range s.releases {
+		if r.TagName == tag {
+			return &r, nil
+		}
+	}
+	return nil, source.ErrNotFound
+}
+
+func (s fakeSource) OptionalRelease(repo, tag string) (*source.Release, error) {
+	return s.GetRelease(repo, tag)
+}
+
+func (s fakeSource) AssetURL(string, string, string) (string, error) { return "", nil }
+func (s fakeSource) DownloadAsset(string, string, string, string) error {
+	return errors.New("not implemented")
+}
+
+func releases(tags ...string) []source.Release {
+	var rs []source.Release
+	for _, tag := range tags {
+		rs = append(rs, source.Release{TagName: tag})
+	}
+	return rs
+}
+
+func TestResolveExactPartialVersion(t *testing.T) {
+	p := &PluginConfig{Name: "tool", Repo: "owner/tool", FormatVersion: func(tag string) string { return tag[1:] }}
+
+	tests := []struct {
+		name string
+		tags []string
+		spec string
+		want string
+	}{
+		{"exact partial tag wins", []string{"v1.2.5", "v1.2", "v1.1"}, "1.2", "v1.2"},
+		{"partial range without exact tag", []string{"v1.2.5", "v1.2.0", "v1.1.0"}, "1.2", "v1.2.5"},
+		{"exact major tag wins", []string{"v2.1.0", "v2"}, "2", "v2"},
+		{"wildcard stays a range", []string{"v1.3.0", "v1.2.0"}, "1.x", "v1.3.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Resolve(fakeSource{releases(tt.tags...)}, tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.TagName != tt.want {
+				t.Errorf("Resolve(%q) = %s, want %s", tt.spec, got.TagName, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsVersionSpec(t *testing.T) {
+	tests := []struct {
+		spec string
+		want bool
+	}{
+		{"latest", true},
+		{"latest-stable", true},
+		{"prerelease", true},
+		{"^1.2", true},
+		{"~1.2.3", true},
+		{">=0.40 <0.50", true},
+		{"1.x", true},
+		{"1.2.*", true},
+		{"1", true},
+		{"1.2", true},
+		{"1.2.3", false},
+		{"1.2.3-rc.1", false},
+		{"2024-01-01", false},
+		{"nightly", false},
+	}
+	for _, tt := range tests {
+		if got := IsVersionSpec(tt.spec); got != tt.want {
+			t.Errorf("IsVersionSpec(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseConstraint(t *testing.T) {
+	tests := []struct {
+		spec  string
+		match []string
+		miss  []string
+	}{
+		{"^1.2", []string{"v1.2.0", "v1.9.9"}, []string{"v1.1.9", "v2.0.0"}},
+		{"^0.10", []string{"v0.10.0", "v0.10.7"}, []string{"v0.11.0", "v0.9.9"}},
+		{"^0.0.3", []string{"v0.0.3"}, []string{"v0.0.4"}},
+		{"~1", []string{"v1.0.0", "v1.9.0"}, []string{"v2.0.0"}},
+		{"~1.2", []string{"v1.2.0", "v1.2.9"}, []string{"v1.3.0"}},
+		{"~1.2.3", []string{"v1.2.3", "v1.2.9"}, []string{"v1.2.2", "v1.3.0"}},
+		{">=0.40 <0.50", []string{"v0.40.0", "v0.49.9"}, []string{"v0.39.9", "v0.50.0"}},
+		{">= 0.40, < 0.50", []string{"v0.45.0"}, []string{"v0.50.0"}},
+		{">1.2", []string{"v1.3.0"}, []string{"v1.2.9"}},
+		{">1.2.3", []string{"v1.2.4"}, []string{"v1.2.3"}},
+		{"<=1.2", []string{"v1.2.9"}, []string{"v1.3.0"}},
+		{"<=1.2.3", []string{"v1.2.3"}, []string{"v1.2.4"}},
+		{"1.x", []string{"v1.0.0", "v1.9.0"}, []string{"v2.0.0", "v0.9.0"}},
+		{"1.2.*", []string{"v1.2.5"}, []string{"v1.3.0"}},
+		{"=1.2.3", []string{"v1.2.3"}, []string{"v1.2.4"}},
+		{"*", []string{"v0.0.1", "v9.0.0"}, nil},
+		{"^1 || ^3", []string{"v1.5.0", "v3.0.0"}, []string{"v2.0.0"}},
+		{">=1.0.0-rc.1 <1.0.0", []string{"v1.0.0-rc.1", "v1.0.0-rc.2"}, []string{"v1.0.0", "v1.0.0-beta.1"}},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.spec)
+		if err != nil {
+			t.Errorf("ParseConstraint(%q) error = %v", tt.spec, err)
+			continue
+		}
+		for _, v := range tt.match {
+			if !c.Match(v) {
+				t.Errorf("%q does not match %s", tt.spec, v)
+			}
+		}
+		for _, v := range tt.miss {
+			if c.Match(v) {
+				t.Errorf("%q matches %s", tt.spec, v)
+			}
+		}
+	}
+
+	for _, spec := range []string{"", "^", "1.2.3.4", "1.x.3", "01.2", "abc", "^1.2-rc.1", ">=1 ||"} {
+		if _, err := ParseConstraint(spec); err == nil {
+			t.Errorf("ParseConstraint(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	p := &PluginConfig{Name: "tool", Repo: "owner/tool", FormatVersion: func(tag string) string { return tag[1:] }}
+	src := fakeSource{append(releases("v1.2.0", "v2.0.0-rc.1", "v1.10.1", "v0.9.0", "v1.10.0"),
+		source.Release{TagName: "v1.11.0", Prerelease: true})}
+
+	tests := []struct {
+		spec string
+		want string // Empty for ErrNoMatch
+	}{
+		{"latest", "v2.0.0-rc.1"},
+		{"latest-stable", "v1.10.1"},
+		{"prerelease", "v2.0.0-rc.1"},
+		// Ranges skip prereleases, whether marked by the forge or the version
+		{"^1", "v1.10.1"},
+		{"~1.2", "v1.2.0"},
+		{"1.10", "v1.10.1"},
+		{"<1", "v0.9.0"},
+		{">=2.0.0-rc.1", "v2.0.0-rc.1"},
+		{"1.10.0", "v1.10.0"},
+		{" 1.10.0 ", "v1.10.0"},
+		{"^3", ""},
+		{"1.3.0", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := p.Resolve(src, tt.spec)
+			if tt.want == "" {
+				if !errors.Is(err, ErrNoMatch) {
+					t.Fatalf("Resolve(%q) = %s, %v, want %v", tt.spec, got.TagName, err, ErrNoMatch)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.TagName != tt.want {
+				t.Errorf("Resolve(%q) = %s, want %s", tt.spec, got.TagName, tt.want)
+			}
+		})
+	}
+
+	if _, err := p.Resolve(src, "^1.x.2"); err == nil || errors.Is(err, ErrNoMatch) {
+		t.Errorf("Resolve() of an invalid range error = %v", err)
+	}
+}
+
+// pagedSource serves its releases in pages, counting the pages fetched
+type pagedSource struct {
+	fakeSource
+	pageSize int
+	pages    *int
+}
+
+func (s pagedSource) ListReleases(_ string, more func([]source.Release) bool) ([]source.Release, error) {
+	var listed []source.Release
+	for start := 0; start < len(s.releases); start += s.pageSize {
+		*s.pages++
+		listed = append(listed, s.releases[start:min(start+s.pageSize, len(s.releases))]...)
+		if more != nil && !more(listed) {
+			break
+		}
+	}
+	return listed, nil
+}
+
+func TestResolveStopsPagingBelowRange(t *testing.T) {
+	p := &PluginConfig{Name: "tool", Repo: "owner/tool", FormatVersion: func(tag string) string { return tag[1:] }}
+	// Newest first, two per page
+	tags := []string{"v3.2.0", "v3.1.0", "v3.0.0", "v2.1.0", "v2.0.0", "v1.5.0", "v1.4.0", "v1.0.0", "v0.9.0"}
+
+	tests := []struct {
+		spec  string
+		want  string // Empty for ErrNoMatch
+		pages int
+	}{
+		{"^3", "v3.2.0", 2},
+		{"~2.0", "v2.0.0", 3},
+		{">=1.4 <2", "v1.5.0", 4},
+		{"1.x || ^3", "v3.2.0", 5},
+		{"^4", "", 1},
+		// No lower bound, so every page is needed
+		{"<1", "v0.9.0", 5},
+		{"latest-stable", "v3.2.0", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			pages := 0
+			src := pagedSource{fakeSource: fakeSource{releases(tags...)}, pageSize: 2, pages: &pages}
+			got, err := p.Resolve(src, tt.spec)
+			switch {
+			case tt.want == "" && !errors.Is(err, ErrNoMatch):
+				t.Errorf("Resolve(%q) = %s, %v, want %v", tt.spec, got.TagName, err, ErrNoMatch)
+			case tt.want != "" && err != nil:
+				t.Errorf("Resolve(%q) error = %v", tt.spec, err)
+			case got.TagName != tt.want:
+				t.Errorf("Resolve(%q) = %s, want %s", tt.spec, got.TagName, tt.want)
+			}
+			if pages != tt.pages {
+				t.Errorf("Resolve(%q) fetched %d pages, want %d", tt.spec, pages, tt.pages)
+			}
+		})
+	}
+}
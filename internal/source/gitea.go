@@ -0,0 +1,121 @@
+package source
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aniaan/sous-chef/internal/auth"
+	"github.com/aniaan/sous-chef/internal/gh"
+)
+
+const (
+	// giteaAPIPath is where Gitea and Forgejo mount the REST API
+	giteaAPIPath = "/api/v1"
+	// giteaPerPage is the default maximum page size of Gitea instances
+	giteaPerPage = 50
+)
+
+// Gitea reads releases through the Gitea API, which Forgejo and Codeberg
+// share
+type Gitea struct {
+	client     *gh.Client
+	apiBaseURL string
+	webBaseURL string
+
+	credentialOnce sync.Once
+	credential     auth.Credential
+	hasCredential  bool
+}
+
+// NewGitea returns a Gitea source. An empty webBaseURL means codeberg.org,
+// and an empty apiBaseURL <webBaseURL>/api/v1.
+func NewGitea(client *gh.Client, apiBaseURL, webBaseURL string) *Gitea {
+	web := strings.TrimSuffix(webBaseURL, "/")
+	api := strings.TrimSuffix(apiBaseURL, "/")
+	if web == "" {
+		web = strings.TrimSuffix(api, giteaAPIPath)
+	}
+	if web == "" {
+		web = DefaultCodebergURL
+	}
+	if api == "" {
+		api = web + giteaAPIPath
+	}
+	return &Gitea{client: client, apiBaseURL: api, webBaseURL: web}
+}
+
+// giteaRelease is a release as returned by the Gitea API
+type giteaRelease struct {
+	TagName     string    `json:"tag_name"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (s *Gitea) Host() string { return hostOf(s.webBaseURL) }
+
+func (s *Gitea) Credential() (auth.Credential, bool) {
+	s.credentialOnce.Do(func() {
+		s.credential, s.hasCredential = auth.GiteaChain().Lookup(s.Host())
+	})
+	return s.credential, s.hasCredential
+}
+
+func (s *Gitea) ListReleases(repo string, more func([]Release) bool) ([]Release, error) {
+	next := fmt.Sprintf("%s/repos/%s/releases?limit=%d", s.apiBaseURL, repo, giteaPerPage)
+	return listPages(s.client, repo, next, s.header(next), fromGitea, more)
+}
+
+func (s *Gitea) GetRelease(repo, tag string) (*Release, error) {
+	u := fmt.Sprintf("%s/repos/%s/releases/tags/%s", s.apiBaseURL, repo, url.PathEscape(tag))
+	var r giteaRelease
+	if _, err := s.client.GetJSON(repo, u, s.header(u), &r); err != nil {
+		return nil, err
+	}
+	release := fromGitea(r)
+	return &release, nil
+}
+
+// OptionalRelease is GetRelease; Gitea has no rate limit reserve
+func (s *Gitea) OptionalRelease(repo, tag string) (*Release, error) {
+	return s.GetRelease(repo, tag)
+}
+
+func (s *Gitea) AssetURL(repo, tag, filename string) (string, error) {
+	return fmt.Sprintf("%s/%s/releases/download/%s/%s", s.webBaseURL, repo, tag, filename), nil
+}
+
+func (s *Gitea) DownloadAsset(repo, tag, filename, destPath string) error {
+	if ok, err := copyLocalAsset(s.client, repo, tag, filename, destPath); ok {
+		return err
+	}
+	u, _ := s.AssetURL(repo, tag, filename)
+	return s.client.Download(u, filename, destPath, s.header(u))
+}
+
+// header authenticates requests to the Gitea host
+func (s *Gitea) header(rawURL string) http.Header {
+	return tokenHeader(s, s.client, rawURL, "Authorization", "token ")
+}
+
+func fromGitea(r giteaRelease) Release {
+	release := Release{
+		TagName:     r.TagName,
+		PublishedAt: r.PublishedAt,
+		Prerelease:  r.Prerelease,
+		Draft:       r.Draft,
+		Assets:      make([]Asset, 0, len(r.Assets)),
+	}
+	for _, a := range r.Assets {
+		release.Assets = append(release.Assets, Asset{Name: a.Name, DownloadURL: a.BrowserDownloadURL})
+	}
+	return release
+}
@@ -0,0 +1,76 @@
+package source
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aniaan/sous-chef/internal/gh"
+)
+
+const giteaReleases = `[
+  {
+    "tag_name": "v1.1.0-beta.1",
+    "draft": false,
+    "prerelease": true,
+    "published_at": "2026-02-01T00:00:00Z",
+    "assets": [
+      {"name": "tool.tar.gz", "browser_download_url": "https://codeberg.org/owner/tool/releases/download/v1.1.0-beta.1/tool.tar.gz"}
+    ]
+  },
+  {
+    "tag_name": "v1.0.0",
+    "draft": false,
+    "prerelease": false,
+    "published_at": "2026-01-01T00:00:00Z",
+    "assets": [
+      {"name": "tool.tar.gz", "browser_download_url": "https://codeberg.org/owner/tool/releases/download/v1.0.0/tool.tar.gz"},
+      {"name": "tool.tar.gz.sha256", "browser_download_url": "https://codeberg.org/owner/tool/releases/download/v1.0.0/tool.tar.gz.sha256"}
+    ]
+  },
+  {
+    "tag_name": "v0.9.0",
+    "draft": true,
+    "prerelease": false,
+    "published_at": "2025-12-01T00:00:00Z",
+    "assets": []
+  }
+]`
+
+func TestGiteaReleases(t *testing.T) {
+	t.Setenv("GITEA_TOKEN", "secret")
+	srv := forgeServer(t, "/api/v1/repos/owner/tool/releases", "Authorization", "token secret", giteaReleases)
+
+	s := NewGitea(gh.NewClient(), srv.URL+"/api/v1", "")
+	got, err := s.ListReleases("owner/tool", nil)
+	if err != nil {
+		t.Fatalf("ListReleases() error = %v", err)
+	}
+	want := []Release{
+		{
+			TagName:     "v1.1.0-beta.1",
+			PublishedAt: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			Prerelease:  true,
+			Assets: []Asset{
+				{Name: "tool.tar.gz", DownloadURL: "https://codeberg.org/owner/tool/releases/download/v1.1.0-beta.1/tool.tar.gz"},
+			},
+		},
+		{
+			TagName:     "v1.0.0",
+			PublishedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			Assets: []Asset{
+				{Name: "tool.tar.gz", DownloadURL: "https://codeberg.org/owner/tool/releases/download/v1.0.0/tool.tar.gz"},
+				{Name: "tool.tar.gz.sha256", DownloadURL: "https://codeberg.org/owner/tool/releases/download/v1.0.0/tool.tar.gz.sha256"},
+			},
+		},
+		{
+			TagName:     "v0.9.0",
+			PublishedAt: time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC),
+			Draft:       true,
+			Assets:      []Asset{},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListReleases() = %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+package source
+
+import (
+	"encoding/json"
+
+	"github.com/aniaan/sous-chef/internal/auth"
+	"github.com/aniaan/sous-chef/internal/gh"
+)
+
+// GitHub reads releases through the GitHub REST API, on github.com or a
+// GitHub Enterprise instance
+type GitHub struct {
+	Client *gh.Client
+}
+
+func (s *GitHub) Host() string { return s.Client.Host() }
+
+func (s *GitHub) Credential() (auth.Credential, bool) { return s.Client.Credential() }
+
+func (s *GitHub) ListReleases(repo string, more func([]Release) bool) ([]Release, error) {
+	// Convert incrementally so more sees every page without redoing earlier ones
+	var releases []Release
+	convert := func(page []gh.Release) []Release {
+		for _, r := range page[len(releases):] {
+			releases = append(releases, fromGitHub(r))
+		}
+		return releases
+	}
+
+	var ghMore func([]gh.Release) bool
+	if more != nil {
+		ghMore = func(page []gh.Release) bool { return more(convert(page)) }
+	}
+	all, err := s.Client.ListReleases(repo, ghMore)
+	if err != nil {
+		return nil, err
+	}
+	return convert(all), nil
+}
+
+func (s *GitHub) GetRelease(repo, tag string) (*Release, error) {
+	r, err := s.Client.GetReleaseByTag(repo, tag)
+	if err != nil {
+		return nil, err
+	}
+	release := fromGitHub(*r)
+	return &release, nil
+}
+
+func (s *GitHub) OptionalRelease(repo, tag string) (*Release, error) {
+	r, err := s.Client.OptionalReleaseByTag(repo, tag)
+	if err != nil {
+		return nil, err
+	}
+	release := fromGitHub(*r)
+	return &release, nil
+}
+
+func (s *GitHub) AssetURL(repo, tag, filename string) (string, error) {
+	return s.Client.AssetURL(repo, tag, filename), nil
+}
+
+func (s *GitHub) DownloadAsset(repo, tag, filename, destPath string) error {
+	if ok, err := copyLocalAsset(s.Client, repo, tag, filename, destPath); ok {
+		return err
+	}
+	return s.Client.DownloadReleaseAsset(repo, tag, filename, destPath)
+}
+
+// Attestations fetches the Sigstore bundles of GitHub artifact attestations
+// for an asset digest (hex SHA-256)
+func (s *GitHub) Attestations(repo, digest string) ([]json.RawMessage, error) {
+	return s.Client.GetAttestations(repo, digest)
+}
+
+func fromGitHub(r gh.Release) Release {
+	release := Release{
+		TagName:     r.TagName,
+		PublishedAt: r.PublishedAt,
+		Prerelease:  r.Prerelease,
+		Draft:       r.Draft,
+		Assets:      make([]Asset, 0, len(r.Assets)),
+	}
+	for _, a := range r.Assets {
+		release.Assets = append(release.Assets, Asset{Name: a.Name, DownloadURL: a.BrowserDownloadURL, Digest: a.Digest})
+	}
+	return release
+}
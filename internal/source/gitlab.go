@@ -0,0 +1,146 @@
+package source
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aniaan/sous-chef/internal/auth"
+	"github.com/aniaan/sous-chef/internal/gh"
+)
+
+const (
+	// gitlabAPIPath is where GitLab mounts the REST API
+	gitlabAPIPath = "/api/v4"
+	// gitlabPerPage is the largest page size the GitLab API accepts
+	gitlabPerPage = 100
+)
+
+// GitLab reads releases through the GitLab Releases API. Repos are project
+// paths such as "group/subgroup/project"; assets are the release's links.
+type GitLab struct {
+	client     *gh.Client
+	apiBaseURL string
+	webBaseURL string
+
+	credentialOnce sync.Once
+	credential     auth.Credential
+	hasCredential  bool
+}
+
+// NewGitLab returns a GitLab source. An empty webBaseURL means gitlab.com,
+// and an empty apiBaseURL <webBaseURL>/api/v4.
+func NewGitLab(client *gh.Client, apiBaseURL, webBaseURL string) *GitLab {
+	web := strings.TrimSuffix(webBaseURL, "/")
+	api := strings.TrimSuffix(apiBaseURL, "/")
+	if web == "" {
+		web = strings.TrimSuffix(api, gitlabAPIPath)
+	}
+	if web == "" {
+		web = DefaultGitLabURL
+	}
+	if api == "" {
+		api = web + gitlabAPIPath
+	}
+	return &GitLab{client: client, apiBaseURL: api, webBaseURL: web}
+}
+
+// gitlabRelease is a release as returned by the GitLab API
+type gitlabRelease struct {
+	TagName         string    `json:"tag_name"`
+	ReleasedAt      time.Time `json:"released_at"`
+	UpcomingRelease bool      `json:"upcoming_release"`
+	Assets          struct {
+		Links []struct {
+			Name           string `json:"name"`
+			URL            string `json:"url"`
+			DirectAssetURL string `json:"direct_asset_url"`
+		} `json:"links"`
+	} `json:"assets"`
+}
+
+func (s *GitLab) Host() string { return hostOf(s.webBaseURL) }
+
+func (s *GitLab) Credential() (auth.Credential, bool) {
+	s.credentialOnce.Do(func() {
+		s.credential, s.hasCredential = auth.GitLabChain().Lookup(s.Host())
+	})
+	return s.credential, s.hasCredential
+}
+
+func (s *GitLab) ListReleases(repo string, more func([]Release) bool) ([]Release, error) {
+	next := fmt.Sprintf("%s/releases?per_page=%d", s.projectURL(repo), gitlabPerPage)
+	return listPages(s.client, repo, next, s.header(next), fromGitLab, more)
+}
+
+func (s *GitLab) GetRelease(repo, tag string) (*Release, error) {
+	u := fmt.Sprintf("%s/releases/%s", s.projectURL(repo), url.PathEscape(tag))
+	var r gitlabRelease
+	if _, err := s.client.GetJSON(repo, u, s.header(u), &r); err != nil {
+		return nil, err
+	}
+	release := fromGitLab(r)
+	return &release, nil
+}
+
+// OptionalRelease is GetRelease; GitLab has no rate limit reserve
+func (s *GitLab) OptionalRelease(repo, tag string) (*Release, error) {
+	return s.GetRelease(repo, tag)
+}
+
+// AssetURL looks the asset up among the release's links, since GitLab asset
+// URLs can point anywhere
+func (s *GitLab) AssetURL(repo, tag, filename string) (string, error) {
+	release, err := s.GetRelease(repo, tag)
+	if err != nil {
+		return "", err
+	}
+	asset, ok := findAsset(release, filename)
+	if !ok {
+		return "", fmt.Errorf("%w: release %s of %s has no asset %s", ErrNotFound, tag, repo, filename)
+	}
+	return asset.DownloadURL, nil
+}
+
+func (s *GitLab) DownloadAsset(repo, tag, filename, destPath string) error {
+	if ok, err := copyLocalAsset(s.client, repo, tag, filename, destPath); ok {
+		return err
+	}
+	u, err := s.AssetURL(repo, tag, filename)
+	if err != nil {
+		return err
+	}
+	return s.client.Download(u, filename, destPath, s.header(u))
+}
+
+// projectURL returns the API URL of a project, addressed by its encoded path
+func (s *GitLab) projectURL(repo string) string {
+	return fmt.Sprintf("%s/projects/%s", s.apiBaseURL, url.PathEscape(repo))
+}
+
+// header authenticates requests to the GitLab host
+func (s *GitLab) header(rawURL string) http.Header {
+	return tokenHeader(s, s.client, rawURL, "PRIVATE-TOKEN", "")
+}
+
+// fromGitLab converts a GitLab release. GitLab has no prerelease flag, so
+// upcoming releases stand in for it.
+func fromGitLab(r gitlabRelease) Release {
+	release := Release{
+		TagName:     r.TagName,
+		PublishedAt: r.ReleasedAt,
+		Prerelease:  r.UpcomingRelease,
+		Assets:      make([]Asset, 0, len(r.Assets.Links)),
+	}
+	for _, link := range r.Assets.Links {
+		u := link.DirectAssetURL
+		if u == "" {
+			u = link.URL
+		}
+		release.Assets = append(release.Assets, Asset{Name: link.Name, DownloadURL: u})
+	}
+	return release
+}
@@ -0,0 +1,82 @@
+package source
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aniaan/sous-chef/internal/gh"
+)
+
+// forgeServer serves body at path, requiring the token header name to be
+// set to token
+func forgeServer(t *testing.T, path, name, token, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != path {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get(name); got != token {
+			t.Errorf("%s header = %q, want %q", name, got, token)
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+const gitlabReleases = `[
+  {
+    "tag_name": "v2.0.0-rc.1",
+    "released_at": "2026-02-01T00:00:00Z",
+    "upcoming_release": true,
+    "assets": {"links": [
+      {"name": "tool.tar.gz", "url": "https://gitlab.com/group/tool/-/package_files/2/download"}
+    ]}
+  },
+  {
+    "tag_name": "v1.0.0",
+    "released_at": "2026-01-01T00:00:00Z",
+    "upcoming_release": false,
+    "assets": {"links": [
+      {
+        "name": "tool.tar.gz",
+        "url": "https://gitlab.com/group/tool/-/package_files/1/download",
+        "direct_asset_url": "https://gitlab.com/group/tool/-/releases/v1.0.0/downloads/tool.tar.gz"
+      }
+    ]}
+  }
+]`
+
+func TestGitLabReleases(t *testing.T) {
+	t.Setenv("GITLAB_TOKEN", "secret")
+	srv := forgeServer(t, "/api/v4/projects/group%2Fsub%2Ftool/releases", "PRIVATE-TOKEN", "secret", gitlabReleases)
+
+	s := NewGitLab(gh.NewClient(), "", srv.URL+"/")
+	got, err := s.ListReleases("group/sub/tool", nil)
+	if err != nil {
+		t.Fatalf("ListReleases() error = %v", err)
+	}
+	want := []Release{
+		{
+			TagName:     "v2.0.0-rc.1",
+			PublishedAt: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			// Upcoming releases stand in for prereleases
+			Prerelease: true,
+			Assets:     []Asset{{Name: "tool.tar.gz", DownloadURL: "https://gitlab.com/group/tool/-/package_files/2/download"}},
+		},
+		{
+			TagName:     "v1.0.0",
+			PublishedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			// direct_asset_url wins over url
+			Assets: []Asset{{Name: "tool.tar.gz", DownloadURL: "https://gitlab.com/group/tool/-/releases/v1.0.0/downloads/tool.tar.gz"}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListReleases() = %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,196 @@
+// Package source fetches releases and release assets from the forge hosting a
+// tool: GitHub, GitLab, or Gitea and Forgejo (including Codeberg), or from a
+// plain download site.
+package source
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aniaan/sous-chef/internal/auth"
+	"github.com/aniaan/sous-chef/internal/gh"
+	"github.com/aniaan/sous-chef/internal/util"
+)
+
+// Type is the kind of forge hosting a tool
+type Type string
+
+const (
+	TypeGitHub Type = "github"
+	TypeGitLab Type = "gitlab"
+	TypeGitea  Type = "gitea" // Also Forgejo and Codeberg
+	TypeURL    Type = "url"   // A version index and a download URL template
+)
+
+// Default web roots of the public instances
+const (
+	DefaultGitLabURL   = "https://gitlab.com"
+	DefaultCodebergURL = "https://codeberg.org"
+)
+
+// ErrNotFound is gh.ErrNotFound, which 404 responses from every forge match
+var ErrNotFound = gh.ErrNotFound
+
+// Release is a release of a tool, independent of the forge
+type Release struct {
+	TagName     string
+	PublishedAt time.Time
+	Prerelease  bool
+	Draft       bool
+	Assets      []Asset
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name        string
+	DownloadURL string
+	Digest      string // "sha256:<hex>" if the forge reports one
+}
+
+// AssetDigest returns the hex SHA-256 the forge reports for an asset, or "" if
+// the asset is missing or has no digest
+func (r *Release) AssetDigest(filename string) string {
+	for _, asset := range r.Assets {
+		if asset.Name != filename {
+			continue
+		}
+		if digest, ok := strings.CutPrefix(asset.Digest, "sha256:"); ok {
+			return digest
+		}
+		return ""
+	}
+	return ""
+}
+
+// Source lists and downloads the releases of repositories on one forge
+type Source interface {
+	// Host is the forge's host name, e.g. github.com
+	Host() string
+	// Credential returns the token used for the host and where it came from
+	Credential() (auth.Credential, bool)
+
+	// ListReleases fetches releases newest first, following pagination.
+	// After each page, more is called with every release fetched so far;
+	// returning false stops early. A nil more fetches all pages, up to the
+	// client's page limit.
+	ListReleases(repo string, more func([]Release) bool) ([]Release, error)
+	// GetRelease fetches the release for a tag
+	GetRelease(repo, tag string) (*Release, error)
+	// OptionalRelease is GetRelease for callers that can do without the
+	// release; it may fail with gh.ErrRateLimitReserved
+	OptionalRelease(repo, tag string) (*Release, error)
+
+	// AssetURL returns the download URL of a release asset
+	AssetURL(repo, tag, filename string) (string, error)
+	// DownloadAsset downloads a release asset to destPath, or copies it from
+	// the client's local mirror
+	DownloadAsset(repo, tag, filename, destPath string) error
+}
+
+// Config selects a tool's forge. Empty base URLs use the public instance.
+type Config struct {
+	Type       Type
+	APIBaseURL string
+	WebBaseURL string
+
+	// TypeURL only
+	VersionsURL    string // Index listing the versions: JSON, HTML or one version per line
+	VersionsPath   string // Selects the versions in a JSON index; empty for text indexes
+	VersionPattern string // Optional regex extracting versions (its first group, if any)
+	DownloadURL    string // Go template for asset URLs; .Asset is the asset filename
+
+	// AssetData returns the data DownloadURL is rendered with for an asset.
+	// The installer sets it to its template context; nil renders with .Tag
+	// and .Asset only.
+	AssetData func(tag, asset string) any
+}
+
+// Open returns the Source for cfg. client carries the HTTP settings, cache
+// and mirrors shared by every forge.
+func Open(client *gh.Client, cfg Config) Source {
+	switch cfg.Type {
+	case TypeGitLab:
+		return NewGitLab(client, cfg.APIBaseURL, cfg.WebBaseURL)
+	case TypeGitea:
+		return NewGitea(client, cfg.APIBaseURL, cfg.WebBaseURL)
+	case TypeURL:
+		return NewURL(client, cfg)
+	default:
+		return &GitHub{Client: client.WithBaseURLs(cfg.APIBaseURL, cfg.WebBaseURL)}
+	}
+}
+
+// listPages follows the Link headers of a paginated JSON array, converting
+// each element, until more returns false or the client's page limit is hit
+func listPages[T any](client *gh.Client, key, next string, header http.Header, convert func(T) Release, more func([]Release) bool) ([]Release, error) {
+	var releases []Release
+	for page := 0; next != "" && page < client.PageLimit(); page++ {
+		var batch []T
+		link, err := client.GetJSON(key, next, header, &batch)
+		if page > 0 && errors.Is(err, gh.ErrOffline) {
+			// Later pages are only cached if an online listing needed them
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range batch {
+			releases = append(releases, convert(r))
+		}
+		if more != nil && !more(releases) {
+			break
+		}
+		next = gh.NextPageURL(link)
+	}
+	return releases, nil
+}
+
+// tokenHeader returns a header setting name to prefix+token, or nil when no
+// token is known or rawURL is not on the source's host (or mirrored away)
+func tokenHeader(s Source, client *gh.Client, rawURL, name, prefix string) http.Header {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host != s.Host() || client.Mirrored(rawURL) {
+		return nil
+	}
+	cred, ok := s.Credential()
+	if !ok {
+		return nil
+	}
+	header := http.Header{}
+	header.Set(name, prefix+cred.Token)
+	return header
+}
+
+// copyLocalAsset copies an asset from the client's local mirror to destPath,
+// reporting whether the mirror has it. Assets named by URL are looked up by
+// their last path segment.
+func copyLocalAsset(client *gh.Client, repo, tag, filename, destPath string) (bool, error) {
+	local, ok := client.LocalAsset(repo, tag, path.Base(filename))
+	if !ok {
+		return false, nil
+	}
+	return true, util.CopyFile(local, destPath)
+}
+
+// findAsset returns the asset of release named filename
+func findAsset(release *Release, filename string) (Asset, bool) {
+	for _, a := range release.Assets {
+		if a.Name == filename {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// hostOf returns the host of a base URL
+func hostOf(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
@@ -0,0 +1,189 @@
+package source
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/aniaan/sous-chef/internal/auth"
+	"github.com/aniaan/sous-chef/internal/gh"
+)
+
+// KeysSegment selects the keys of a JSON object in a VersionsPath
+const KeysSegment = "@keys"
+
+// URL lists versions from an index on a download site and downloads assets
+// from a URL template, for tools not published as forge releases. Versions are
+// the tags; FormatVersion maps them to display versions as usual.
+type URL struct {
+	client  *gh.Client
+	cfg     Config
+	pattern *regexp.Regexp
+}
+
+// NewURL returns a URL source. cfg.VersionPattern must compile.
+func NewURL(client *gh.Client, cfg Config) *URL {
+	s := &URL{client: client, cfg: cfg}
+	if cfg.VersionPattern != "" {
+		s.pattern = regexp.MustCompile(cfg.VersionPattern)
+	}
+	return s
+}
+
+func (s *URL) Host() string { return hostOf(s.cfg.VersionsURL) }
+
+// Credential always reports none; download sites are fetched anonymously
+func (s *URL) Credential() (auth.Credential, bool) { return auth.Credential{}, false }
+
+// ListReleases fetches the whole index; more is not consulted since indexes
+// are not paginated. Releases carry only a tag.
+func (s *URL) ListReleases(repo string, _ func([]Release) bool) ([]Release, error) {
+	var raw []string
+	if s.cfg.VersionsPath != "" {
+		var index any
+		if _, err := s.client.GetJSON(repo, s.cfg.VersionsURL, nil, &index); err != nil {
+			return nil, err
+		}
+		var err error
+		if raw, err = selectJSON(index, s.cfg.VersionsPath); err != nil {
+			return nil, fmt.Errorf("%s: %w", s.cfg.VersionsURL, err)
+		}
+	} else {
+		text, err := s.client.GetText(repo, s.cfg.VersionsURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if s.pattern != nil {
+			// The pattern extracts versions from the whole body, e.g. HTML links
+			raw = []string{text}
+		} else {
+			raw = strings.Split(text, "\n")
+		}
+	}
+
+	seen := map[string]bool{}
+	var releases []Release
+	for _, v := range raw {
+		for _, version := range s.extract(v) {
+			if !seen[version] {
+				seen[version] = true
+				releases = append(releases, Release{TagName: version})
+			}
+		}
+	}
+	return releases, nil
+}
+
+// extract returns the versions in v: every match of VersionPattern (its first
+// group if it has one), or v itself, trimmed, without a pattern
+func (s *URL) extract(v string) []string {
+	if s.pattern == nil {
+		v = strings.TrimSpace(v)
+		if v == "" || strings.HasPrefix(v, "#") {
+			return nil
+		}
+		return []string{v}
+	}
+
+	var versions []string
+	for _, m := range s.pattern.FindAllStringSubmatch(v, -1) {
+		if len(m) > 1 {
+			versions = append(versions, m[1])
+		} else {
+			versions = append(versions, m[0])
+		}
+	}
+	return versions
+}
+
+// GetRelease returns a release for tag without fetching anything; a missing
+// version surfaces as a 404 on download
+func (s *URL) GetRelease(_, tag string) (*Release, error) {
+	return &Release{TagName: tag}, nil
+}
+
+func (s *URL) OptionalRelease(repo, tag string) (*Release, error) {
+	return s.GetRelease(repo, tag)
+}
+
+// AssetURL renders DownloadURL for an asset. An asset name that is already a
+// URL, such as a checksum asset_template pointing elsewhere, is used as is.
+func (s *URL) AssetURL(_, tag, filename string) (string, error) {
+	if strings.Contains(filename, "://") {
+		return filename, nil
+	}
+
+	var data any = struct{ Tag, Asset string }{tag, filename}
+	if s.cfg.AssetData != nil {
+		data = s.cfg.AssetData(tag, filename)
+	}
+	tmpl, err := template.New("download_url").Parse(s.cfg.DownloadURL)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (s *URL) DownloadAsset(repo, tag, filename, destPath string) error {
+	if ok, err := copyLocalAsset(s.client, repo, tag, filename, destPath); ok {
+		return err
+	}
+	u, err := s.AssetURL(repo, tag, filename)
+	if err != nil {
+		return err
+	}
+	return s.client.Download(u, filename, destPath, nil)
+}
+
+// selectJSON returns the strings at path, a dot-separated list of object keys,
+// "*" for every element of an array or value of an object, and KeysSegment for
+// the keys of an object. "*.version" selects the version of each object in an
+// array, "versions.@keys" the keys of the versions object.
+func selectJSON(v any, path string) ([]string, error) {
+	values := []any{v}
+	for segment := range strings.SplitSeq(path, ".") {
+		var next []any
+		for _, value := range values {
+			switch value := value.(type) {
+			case []any:
+				if segment != "*" {
+					return nil, fmt.Errorf("versions_path: %q applied to an array", segment)
+				}
+				next = append(next, value...)
+			case map[string]any:
+				switch segment {
+				case "*":
+					for _, item := range value {
+						next = append(next, item)
+					}
+				case KeysSegment:
+					for key := range value {
+						next = append(next, key)
+					}
+				default:
+					if item, ok := value[segment]; ok {
+						next = append(next, item)
+					}
+				}
+			}
+		}
+		values = next
+	}
+
+	var versions []string
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			versions = append(versions, s)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, errors.New("versions_path matched no strings")
+	}
+	return versions, nil
+}
@@ -0,0 +1,36 @@
+package source
+
+import (
+	"testing"
+
+	"github.com/aniaan/sous-chef/internal/gh"
+)
+
+func TestURLAssetURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  string
+		assetData func(tag, asset string) any
+		filename  string
+		want      string
+	}{
+		{"tag and asset", "https://dl.example.com/{{.Tag}}/{{.Asset}}", nil, "tool.tar.gz", "https://dl.example.com/1.2.3/tool.tar.gz"},
+		{"asset data", "https://dl.example.com/{{.Version}}/{{.Os}}/{{.Asset}}", func(_, asset string) any {
+			return struct{ Version, Os, Asset string }{"1.2.3", "linux", asset}
+		}, "tool.tar.gz", "https://dl.example.com/1.2.3/linux/tool.tar.gz"},
+		{"asset already a url", "https://dl.example.com/{{.Asset}}", nil, "https://sums.example.com/SHA256SUMS", "https://sums.example.com/SHA256SUMS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := Open(gh.NewClient(), Config{Type: TypeURL, DownloadURL: tt.template, AssetData: tt.assetData})
+			got, err := src.AssetURL("", "1.2.3", tt.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("AssetURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
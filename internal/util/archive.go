@@ -3,15 +3,13 @@ package util
 import (
 	"archive/tar"
 	"archive/zip"
-	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
-
-	"github.com/ulikunitz/xz"
 )
 
 var errIllegalPath = errors.New("illegal file path in archive")
@@ -42,44 +40,41 @@ func stripPath(name string, stripComponents int) string {
 	return filepath.Join(parts[stripComponents:]...)
 }
 
-// ExtractTarGz extracts a .tar.gz archive to dest, stripping stripComponents directories.
-// Only regular files and directories are extracted; symlinks are skipped.
-func ExtractTarGz(src, dest string, stripComponents int) error {
+// tarExtractor extracts tarballs, decompressing them with open if set
+type tarExtractor struct {
+	open decompressor
+}
+
+func (e tarExtractor) Extract(src, dest string, opts ExtractOptions) error {
 	f, err := os.Open(src)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	gzr, err := gzip.NewReader(f)
-	if err != nil {
-		return err
+	var r io.Reader = f
+	if e.open != nil {
+		dr, err := e.open(f)
+		if err != nil {
+			return err
+		}
+		defer dr.Close()
+		r = dr
 	}
-	defer gzr.Close()
-
-	return extractTar(gzr, dest, stripComponents)
+	return extractTar(r, dest, opts)
 }
 
-// ExtractTarXz extracts a .tar.xz archive to dest, stripping stripComponents directories.
-// Only regular files and directories are extracted; symlinks are skipped.
-func ExtractTarXz(src, dest string, stripComponents int) error {
-	f, err := os.Open(src)
+// extractTar extracts directories, regular files, symlinks and hard links.
+// Entries are written through an os.Root, so even a path that passes through
+// an extracted symlink cannot land outside dest. Other entry types are skipped.
+func extractTar(r io.Reader, dest string, opts ExtractOptions) error {
+	root, err := openRoot(dest)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	defer root.Close()
 
-	xzr, err := xz.NewReader(f)
-	if err != nil {
-		return err
-	}
-
-	return extractTar(xzr, dest, stripComponents)
-}
-
-func extractTar(r io.Reader, dest string, stripComponents int) error {
 	tr := tar.NewReader(r)
-
 	for {
 		header, err := tr.Next()
 		if errors.Is(err, io.EOF) {
@@ -89,36 +84,76 @@ func extractTar(r io.Reader, dest string, stripComponents int) error {
 			return err
 		}
 
-		relPath := stripPath(header.Name, stripComponents)
-		if relPath == "" {
+		relPath, ok := opts.entryPath(header.Name)
+		if !ok {
 			continue
 		}
-
-		target, err := sanitizePath(dest, relPath)
-		if err != nil {
+		if _, err := sanitizePath(dest, relPath); err != nil {
+			return err
+		}
+		if err := checkParents(root, relPath); err != nil {
 			return err
 		}
 
 		switch header.Typeflag {
 		case tar.TypeDir:
-			if err := os.MkdirAll(target, 0o755); err != nil {
+			if err := root.MkdirAll(relPath, 0o755); err != nil {
 				return err
 			}
 		case tar.TypeReg:
-			if err := extractTarFile(target, header.Mode, tr); err != nil {
+			if err := extractFile(root, relPath, os.FileMode(header.Mode).Perm(), tr); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := extractSymlink(root, dest, relPath, header.Linkname); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			// Hard link names are archive paths of earlier entries
+			oldPath := stripPath(header.Linkname, opts.StripComponents)
+			if oldPath == "" {
+				return fmt.Errorf("%w: %s links to stripped %s", errIllegalPath, header.Name, header.Linkname)
+			}
+			if _, err := sanitizePath(dest, oldPath); err != nil {
+				return err
+			}
+			if err := replaceEntry(root, relPath); err != nil {
+				return err
+			}
+			if err := root.Link(oldPath, relPath); err != nil {
 				return err
 			}
 		}
 	}
+	return checkSymlinks(dest)
+}
+
+// openRoot creates dest if needed and opens it as an os.Root
+func openRoot(dest string) (*os.Root, error) {
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return nil, err
+	}
+	return os.OpenRoot(dest)
+}
+
+// replaceEntry prepares name for a new entry: its parent directories exist
+// and an entry from earlier in the archive is removed
+func replaceEntry(root *os.Root, name string) error {
+	if err := root.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	if err := root.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
 	return nil
 }
 
-func extractTarFile(target string, mode int64, r io.Reader) (err error) {
-	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+func extractFile(root *os.Root, name string, mode os.FileMode, r io.Reader) (err error) {
+	if err := replaceEntry(root, name); err != nil {
 		return err
 	}
 
-	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(mode))
+	f, err := root.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
 	if err != nil {
 		return err
 	}
@@ -132,25 +167,102 @@ func extractTarFile(target string, mode int64, r io.Reader) (err error) {
 	return err
 }
 
-// ExtractGz extracts a single .gz file to dest.
-func ExtractGz(src, dest string) (err error) {
-	f, err := os.Open(src)
+// extractSymlink creates a symlink at name. Like entry names, its target must
+// be relative and resolve inside dest.
+func extractSymlink(root *os.Root, dest, name, linkname string) error {
+	linkname = filepath.FromSlash(linkname)
+	if linkname == "" || filepath.IsAbs(linkname) {
+		return fmt.Errorf("%w: %s -> %s", errIllegalPath, name, linkname)
+	}
+	if _, err := sanitizePath(dest, filepath.Join(filepath.Dir(name), linkname)); err != nil {
+		return fmt.Errorf("%w: %s -> %s", errIllegalPath, name, linkname)
+	}
+	if err := replaceEntry(root, name); err != nil {
+		return err
+	}
+	return root.Symlink(linkname, name)
+}
+
+// checkParents rejects an entry below a symlink extracted earlier. Symlink
+// targets are checked against the entry's path, which is not where the link
+// resolves once a parent is itself a link, as in d -> . then d/x -> ../y.
+func checkParents(root *os.Root, name string) error {
+	for dir := filepath.Dir(name); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		info, err := root.Lstat(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %s is below the symlink %s", errIllegalPath, name, dir)
+		}
+	}
+	return nil
+}
+
+// checkSymlinks resolves every symlink under dest once extraction is done and
+// rejects those leading outside it. Targets passing through another link,
+// such as x -> . and a -> x/.., only escape once both exist. Dangling links
+// are left alone.
+func checkSymlinks(dest string) error {
+	realDest, err := filepath.EvalSymlinks(dest)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	return filepath.WalkDir(dest, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return err
+		}
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return nil
+		}
+		if rel, err := filepath.Rel(realDest, target); err != nil || !filepath.IsLocal(rel) {
+			name, _ := filepath.Rel(dest, path)
+			return fmt.Errorf("%w: %s resolves outside the archive", errIllegalPath, name)
+		}
+		return nil
+	})
+}
 
-	gzr, err := gzip.NewReader(f)
+// fileExtractor writes a single file, decompressed with open if set, to
+// opts.Target; with no decompressor the asset is the binary itself
+type fileExtractor struct {
+	open decompressor
+}
+
+func (e fileExtractor) Extract(src, dest string, opts ExtractOptions) (err error) {
+	if opts.Target == "" {
+		return errors.New("no target path for a single-file asset")
+	}
+	target, err := sanitizePath(dest, opts.Target)
 	if err != nil {
 		return err
 	}
-	defer gzr.Close()
 
-	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if e.open != nil {
+		dr, err := e.open(f)
+		if err != nil {
+			return err
+		}
+		defer dr.Close()
+		r = dr
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
 		return err
 	}
 
-	out, err := os.Create(dest)
+	out, err := os.Create(target)
 	if err != nil {
 		return err
 	}
@@ -160,48 +272,53 @@ func ExtractGz(src, dest string) (err error) {
 		}
 	}()
 
-	_, err = io.Copy(out, gzr)
+	_, err = io.Copy(out, r)
 	return err
 }
 
-// ExtractZip extracts a .zip archive to dest, stripping stripComponents directories.
-func ExtractZip(src, dest string, stripComponents int) error {
+// zipExtractor extracts zip archives. Entries with the symlink mode bit
+// become symlinks.
+type zipExtractor struct{}
+
+func (zipExtractor) Extract(src, dest string, opts ExtractOptions) error {
 	r, err := zip.OpenReader(src)
 	if err != nil {
 		return err
 	}
 	defer r.Close()
 
-	absDest, err := filepath.Abs(dest)
+	root, err := openRoot(dest)
 	if err != nil {
 		return err
 	}
+	defer root.Close()
 
 	for _, f := range r.File {
-		if err := extractZipFile(f, absDest, stripComponents); err != nil {
+		if err := extractZipFile(f, root, dest, opts); err != nil {
 			return err
 		}
 	}
-	return nil
+	return checkSymlinks(dest)
 }
 
-func extractZipFile(f *zip.File, dest string, stripComponents int) (err error) {
-	relPath := stripPath(f.Name, stripComponents)
-	if relPath == "" {
+// maxSymlinkTarget bounds the size of a zip symlink entry, whose content is
+// the link target
+const maxSymlinkTarget = 4096
+
+func extractZipFile(f *zip.File, root *os.Root, dest string, opts ExtractOptions) error {
+	relPath, ok := opts.entryPath(f.Name)
+	if !ok {
 		return nil
 	}
-
-	target, err := sanitizePath(dest, relPath)
-	if err != nil {
+	if _, err := sanitizePath(dest, relPath); err != nil {
 		return err
 	}
-
-	if f.FileInfo().IsDir() {
-		return os.MkdirAll(target, 0o755)
+	if err := checkParents(root, relPath); err != nil {
+		return err
 	}
 
-	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
-		return err
+	if f.FileInfo().IsDir() {
+		return root.MkdirAll(relPath, 0o755)
 	}
 
 	rc, err := f.Open()
@@ -210,18 +327,14 @@ func extractZipFile(f *zip.File, dest string, stripComponents int) (err error) {
 	}
 	defer rc.Close()
 
-	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.Mode())
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if cerr := out.Close(); err == nil {
-			err = cerr
+	if f.Mode()&os.ModeSymlink != 0 {
+		linkname, err := io.ReadAll(io.LimitReader(rc, maxSymlinkTarget))
+		if err != nil {
+			return err
 		}
-	}()
-
-	_, err = io.Copy(out, rc)
-	return err
+		return extractSymlink(root, dest, relPath, string(linkname))
+	}
+	return extractFile(root, relPath, f.Mode().Perm(), rc)
 }
 
 // CopyFile copies a file from src to dst.
@@ -0,0 +1,237 @@
+package util
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tarEntry is one entry of a test tarball
+type tarEntry struct {
+	name     string
+	typeflag byte
+	linkname string
+	body     string
+}
+
+func writeTar(t *testing.T, entries []tarEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0o755, Size: int64(len(e.body))}
+		if e.typeflag != tar.TypeReg {
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if e.typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "asset.tar")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// extractDir returns an empty dest directory next to a file outside it
+func extractDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "secret"), []byte("outside"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "dest")
+}
+
+func TestExtractTarRejectsEscapes(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+	}{
+		{"dot-dot entry", []tarEntry{{name: "../secret", typeflag: tar.TypeReg, body: "x"}}},
+		{"absolute symlink", []tarEntry{{name: "link", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"}}},
+		{"symlink out", []tarEntry{{name: "link", typeflag: tar.TypeSymlink, linkname: "../secret"}}},
+		{"nested symlink out", []tarEntry{{name: "a/b/link", typeflag: tar.TypeSymlink, linkname: "../../../secret"}}},
+		{"symlink below directory symlink", []tarEntry{
+			{name: "d", typeflag: tar.TypeSymlink, linkname: "."},
+			{name: "d/evil", typeflag: tar.TypeSymlink, linkname: "../secret"},
+		}},
+		{"file below directory symlink", []tarEntry{
+			{name: "d", typeflag: tar.TypeSymlink, linkname: "."},
+			{name: "d/file", typeflag: tar.TypeReg, body: "x"},
+		}},
+		{"symlink through later symlink", []tarEntry{
+			{name: "a", typeflag: tar.TypeSymlink, linkname: "x/.."},
+			{name: "x", typeflag: tar.TypeSymlink, linkname: "."},
+		}},
+		{"hard link out", []tarEntry{{name: "link", typeflag: tar.TypeLink, linkname: "../secret"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := writeTar(t, tt.entries)
+			dest := extractDir(t)
+			err := Extract(src, "asset.tar", dest, FormatTar, ExtractOptions{})
+			if !errors.Is(err, errIllegalPath) {
+				t.Fatalf("Extract() error = %v, want %v", err, errIllegalPath)
+			}
+			if got, _ := os.ReadFile(filepath.Join(dest, "..", "secret")); string(got) != "outside" {
+				t.Errorf("file outside dest = %q", got)
+			}
+		})
+	}
+}
+
+func TestExtractTarLinks(t *testing.T) {
+	src := writeTar(t, []tarEntry{
+		{name: "pkg/lib/libfoo.so.1", typeflag: tar.TypeReg, body: "lib"},
+		{name: "pkg/lib/libfoo.so", typeflag: tar.TypeSymlink, linkname: "libfoo.so.1"},
+		{name: "pkg/bin/tool", typeflag: tar.TypeReg, body: "tool"},
+		{name: "pkg/bin/alias", typeflag: tar.TypeLink, linkname: "pkg/bin/tool"},
+		{name: "pkg/bin/dot", typeflag: tar.TypeSymlink, linkname: "."},
+	})
+	dest := extractDir(t)
+	if err := Extract(src, "asset.tar", dest, FormatTar, ExtractOptions{StripComponents: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"lib/libfoo.so": "lib",
+		"bin/alias":     "tool",
+		"bin/dot/tool":  "tool",
+	} {
+		got, err := os.ReadFile(filepath.Join(dest, name))
+		if err != nil {
+			t.Errorf("ReadFile(%s): %v", name, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestExtractZipRejectsSymlinkEscape(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range []struct{ name, target string }{{"d", "."}, {"d/evil", "../secret"}} {
+		hdr := &zip.FileHeader{Name: e.name}
+		hdr.SetMode(os.ModeSymlink | 0o777)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.target)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(t.TempDir(), "asset.zip")
+	if err := os.WriteFile(src, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := extractDir(t)
+	err := Extract(src, "asset.zip", dest, FormatZip, ExtractOptions{})
+	if !errors.Is(err, errIllegalPath) {
+		t.Fatalf("Extract() error = %v, want %v", err, errIllegalPath)
+	}
+	if _, err := os.ReadFile(filepath.Join(dest, "d", "evil")); err == nil {
+		t.Error("d/evil resolves outside dest")
+	}
+}
+
+func TestSanitizePath(t *testing.T) {
+	dest := t.TempDir()
+	tests := []struct {
+		name    string
+		want    string // Relative to dest; empty when rejected
+		wantErr bool
+	}{
+		{"bin/tool", "bin/tool", false},
+		{"./bin/tool", "bin/tool", false},
+		{"bin/../tool", "tool", false},
+		{".", ".", false},
+		{"../tool", "", true},
+		{"bin/../../tool", "", true},
+		{"/etc/passwd", "etc/passwd", false}, // Joined below dest, not absolute
+		{"..", "", true},
+	}
+	for _, tt := range tests {
+		got, err := sanitizePath(dest, tt.name)
+		if tt.wantErr {
+			if !errors.Is(err, errIllegalPath) {
+				t.Errorf("sanitizePath(%q) = %q, %v, want %v", tt.name, got, err, errIllegalPath)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("sanitizePath(%q) error = %v", tt.name, err)
+			continue
+		}
+		if want := filepath.Join(dest, tt.want); got != want {
+			t.Errorf("sanitizePath(%q) = %q, want %q", tt.name, got, want)
+		}
+	}
+}
+
+func TestExtractSymlink(t *testing.T) {
+	tests := []struct {
+		name     string
+		linkname string
+		wantErr  bool
+	}{
+		{"lib/libfoo.so", "libfoo.so.1", false},
+		{"bin/tool", "../libexec/tool", false},
+		{"current", ".", false},
+		{"bin/tool", "../../tool", true},
+		{"tool", "../secret", true},
+		{"tool", "/usr/bin/tool", true},
+		{"tool", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" -> "+tt.linkname, func(t *testing.T) {
+			dest := extractDir(t)
+			if err := os.MkdirAll(filepath.Join(dest, filepath.Dir(tt.name)), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			root, err := os.OpenRoot(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer root.Close()
+
+			err = extractSymlink(root, dest, tt.name, tt.linkname)
+			if tt.wantErr {
+				if !errors.Is(err, errIllegalPath) {
+					t.Fatalf("extractSymlink() error = %v, want %v", err, errIllegalPath)
+				}
+				if _, err := os.Lstat(filepath.Join(dest, tt.name)); err == nil {
+					t.Error("rejected symlink was created")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := os.Readlink(filepath.Join(dest, tt.name)); err != nil || got != tt.linkname {
+				t.Errorf("Readlink() = %q, %v, want %q", got, err, tt.linkname)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const appName = "sous-chef"
+
+// ConfigDir returns the sous-chef configuration directory.
+// It honors XDG_CONFIG_HOME and falls back to ~/.config/sous-chef.
+func ConfigDir() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// CacheDir returns the sous-chef cache directory.
+// It honors XDG_CACHE_HOME and falls back to ~/.cache/sous-chef.
+func CacheDir() (string, error) {
+	return xdgDir("XDG_CACHE_HOME", ".cache")
+}
+
+func xdgDir(env, fallback string) (string, error) {
+	if dir := os.Getenv(env); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, fallback, appName), nil
+}
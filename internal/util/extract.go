@@ -0,0 +1,188 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownFormat is returned for formats no extractor is registered for
+var ErrUnknownFormat = errors.New("unknown archive format")
+
+// Extractor unpacks assets of one format into a directory
+type Extractor interface {
+	// Extract unpacks src into the directory dest
+	Extract(src, dest string, opts ExtractOptions) error
+}
+
+// ExtractOptions selects what is extracted and where. Patterns use path.Match
+// syntax against slash-separated entry names after stripping. A pattern
+// without a slash matches a name at any depth, as in .gitignore, and a
+// pattern matching a directory covers everything below it.
+type ExtractOptions struct {
+	// StripComponents drops this many leading directories from entry names
+	StripComponents int
+	// Include, if not empty, extracts only entries matching one of its patterns
+	Include []string
+	// Exclude skips entries matching one of its patterns
+	Exclude []string
+	// Target is the path, relative to dest, that single-file formats write to
+	Target string
+}
+
+// entryPath returns the path an archive entry is extracted to, and false if
+// stripping or the filters drop it
+func (o ExtractOptions) entryPath(name string) (string, bool) {
+	rel := stripPath(name, o.StripComponents)
+	if rel == "" || rel == "." {
+		return "", false
+	}
+	slashed := filepath.ToSlash(rel)
+	if len(o.Include) > 0 && !matchAny(o.Include, slashed) {
+		return "", false
+	}
+	if matchAny(o.Exclude, slashed) {
+		return "", false
+	}
+	return rel, true
+}
+
+// matchAny reports whether name, or a directory containing it, matches one of
+// the patterns
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		anyDepth := !strings.Contains(pattern, "/")
+		for n := name; n != "." && n != "/"; n = path.Dir(n) {
+			candidate := n
+			if anyDepth {
+				candidate = path.Base(n)
+			}
+			if ok, _ := path.Match(pattern, candidate); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckPatterns reports the first malformed include or exclude pattern
+func CheckPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// registration is a format known to the extractor registry
+type registration struct {
+	format    Format
+	extractor Extractor
+	suffixes  []string
+	magic     func(r io.Reader) bool
+}
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   []registration // In sniffing order
+)
+
+// RegisterExtractor makes e handle format. suffixes are the file name
+// suffixes the format is known by, and magic, if not nil, reports whether
+// content read from the start of a file is in the format. DetectFormat tries
+// formats in registration order, so containers such as tar.gz must come
+// before the compression they use. Registering a format again replaces it in
+// place.
+func RegisterExtractor(format Format, e Extractor, magic func(r io.Reader) bool, suffixes ...string) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+
+	reg := registration{format: format, extractor: e, suffixes: suffixes, magic: magic}
+	for i, existing := range extractors {
+		if existing.format == format {
+			extractors[i] = reg
+			return
+		}
+	}
+	extractors = append(extractors, reg)
+}
+
+// ExtractorFor returns the extractor registered for format
+func ExtractorFor(format Format) (Extractor, bool) {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+
+	for _, reg := range extractors {
+		if reg.format == format {
+			return reg.extractor, true
+		}
+	}
+	return nil, false
+}
+
+// FormatFromName returns the format whose longest registered suffix ends the
+// file name, or FormatRaw if none does
+func FormatFromName(name string) Format {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+
+	name = strings.ToLower(name)
+	format, longest := FormatRaw, 0
+	for _, reg := range extractors {
+		for _, suffix := range reg.suffixes {
+			if len(suffix) > longest && strings.HasSuffix(name, suffix) {
+				format, longest = reg.format, len(suffix)
+			}
+		}
+	}
+	return format
+}
+
+// DetectFormat identifies a file's format from its magic bytes. Files no
+// registered format claims, such as executables, are FormatRaw.
+func DetectFormat(path string) (Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return FormatRaw, err
+	}
+	defer f.Close()
+
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+
+	for _, reg := range extractors {
+		if reg.magic == nil {
+			continue
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return FormatRaw, err
+		}
+		if reg.magic(f) {
+			return reg.format, nil
+		}
+	}
+	return FormatRaw, nil
+}
+
+// Extract unpacks src into dest with the extractor for format, detecting the
+// format from magic bytes, then from name, when format is empty
+func Extract(src, name, dest string, format Format, opts ExtractOptions) error {
+	if format == "" {
+		var err error
+		if format, err = AssetFormat(src, name); err != nil {
+			return err
+		}
+	}
+
+	e, ok := ExtractorFor(format)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	return e.Extract(src, dest, opts)
+}
@@ -0,0 +1,108 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// restoreExtractors undoes the test's registrations when it ends
+func restoreExtractors(t *testing.T) {
+	t.Helper()
+	extractorsMu.RLock()
+	saved := append([]registration(nil), extractors...)
+	extractorsMu.RUnlock()
+	t.Cleanup(func() {
+		extractorsMu.Lock()
+		extractors = saved
+		extractorsMu.Unlock()
+	})
+}
+
+// recordingExtractor records the calls it gets
+type recordingExtractor struct {
+	calls []string
+}
+
+func (e *recordingExtractor) Extract(src, dest string, opts ExtractOptions) error {
+	e.calls = append(e.calls, src+" -> "+dest)
+	return nil
+}
+
+const formatTest Format = "test"
+
+func TestRegisterExtractorReplacesInPlace(t *testing.T) {
+	restoreExtractors(t)
+	first, second := &recordingExtractor{}, &recordingExtractor{}
+
+	RegisterExtractor(formatTest, first, nil, ".test")
+	n := len(extractors)
+	index := func() int {
+		for i, reg := range extractors {
+			if reg.format == formatTest {
+				return i
+			}
+		}
+		return -1
+	}
+	before := index()
+
+	RegisterExtractor(formatTest, second, nil, ".test2")
+	if len(extractors) != n || index() != before {
+		t.Errorf("re-registering moved the format from %d to %d (%d -> %d formats)", before, index(), n, len(extractors))
+	}
+	if e, ok := ExtractorFor(formatTest); !ok || e != second {
+		t.Errorf("ExtractorFor() = %v, %v; want the replacement", e, ok)
+	}
+	if got := FormatFromName("tool.test2"); got != formatTest {
+		t.Errorf("FormatFromName() = %s, want %s from the replacement's suffixes", got, formatTest)
+	}
+	if got := FormatFromName("tool.test"); got == formatTest {
+		t.Errorf("FormatFromName() still matches the replaced suffix")
+	}
+}
+
+func TestExtractCustomFormat(t *testing.T) {
+	restoreExtractors(t)
+	custom := &recordingExtractor{}
+	magic := []byte("TESTFMT")
+	RegisterExtractor(formatTest, custom, func(r io.Reader) bool {
+		head := make([]byte, len(magic))
+		_, err := io.ReadFull(r, head)
+		return err == nil && bytes.Equal(head, magic)
+	}, ".test")
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "asset")
+	if err := os.WriteFile(src, append(magic, "payload"...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "out")
+
+	// Detected by magic bytes, despite the name
+	if err := Extract(src, "asset.zip", dest, "", ExtractOptions{}); err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	// Selected explicitly, as by archive_format
+	if err := Extract(src, "asset", dest, formatTest, ExtractOptions{}); err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if len(custom.calls) != 2 {
+		t.Errorf("custom extractor called %d times, want 2", len(custom.calls))
+	}
+}
+
+func TestExtractUnknownFormat(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "asset")
+	if err := os.WriteFile(src, []byte("payload"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := Extract(src, "asset", filepath.Join(dir, "out"), Format("rar"), ExtractOptions{})
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Extract() error = %v, want %v", err, ErrUnknownFormat)
+	}
+}
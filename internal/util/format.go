@@ -0,0 +1,205 @@
+package util
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Format is the archive or compression format of a release asset
+type Format string
+
+const (
+	FormatRaw    Format = "raw"     // Not an archive: the asset is the binary
+	FormatTar    Format = "tar"     // Uncompressed tarball
+	FormatTarGz  Format = "tar.gz"  // Also .tgz
+	FormatTarXz  Format = "tar.xz"  // Also .txz
+	FormatTarZst Format = "tar.zst" // Also .tzst
+	FormatTarBz2 Format = "tar.bz2" // Also .tbz2 and .tbz
+	FormatZip    Format = "zip"
+	FormatGz     Format = "gz"  // A single gzip-compressed file
+	FormatXz     Format = "xz"  // A single xz-compressed file
+	FormatZst    Format = "zst" // A single zstd-compressed file
+	FormatBz2    Format = "bz2" // A single bzip2-compressed file
+)
+
+// decompressor opens a decompressing reader over r
+type decompressor func(r io.Reader) (io.ReadCloser, error)
+
+// compression is a compression format, recognized by its magic bytes, with
+// the formats of a compressed tarball and a single compressed file
+type compression struct {
+	magic       []byte
+	open        decompressor
+	tar         Format
+	tarSuffixes []string
+	single      Format
+}
+
+var compressions = []compression{
+	{
+		magic: []byte{0x1f, 0x8b},
+		open: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		tar: FormatTarGz, tarSuffixes: []string{".tar.gz", ".tgz"},
+		single: FormatGz,
+	},
+	{
+		magic: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00},
+		open: func(r io.Reader) (io.ReadCloser, error) {
+			xzr, err := xz.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return io.NopCloser(xzr), nil
+		},
+		tar: FormatTarXz, tarSuffixes: []string{".tar.xz", ".txz"},
+		single: FormatXz,
+	},
+	{
+		magic: []byte{0x28, 0xb5, 0x2f, 0xfd},
+		open: func(r io.Reader) (io.ReadCloser, error) {
+			// One goroutine is plenty for a single sequential stream
+			zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, err
+			}
+			return zr.IOReadCloser(), nil
+		},
+		tar: FormatTarZst, tarSuffixes: []string{".tar.zst", ".tzst"},
+		single: FormatZst,
+	},
+	{
+		magic: []byte("BZh"),
+		open: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(bzip2.NewReader(r)), nil
+		},
+		tar: FormatTarBz2, tarSuffixes: []string{".tar.bz2", ".tbz2", ".tbz"},
+		single: FormatBz2,
+	},
+}
+
+// The built-in formats. Compressed tarballs are registered before single
+// compressed files, which share their magic bytes.
+func init() {
+	RegisterExtractor(FormatZip, zipExtractor{}, hasMagic([]byte("PK\x03\x04"), []byte("PK\x05\x06")), ".zip")
+	RegisterExtractor(FormatTar, tarExtractor{}, isTar, ".tar")
+	for _, c := range compressions {
+		RegisterExtractor(c.tar, tarExtractor{open: c.open}, compressedTar(c), c.tarSuffixes...)
+	}
+	for _, c := range compressions {
+		RegisterExtractor(c.single, fileExtractor{open: c.open}, hasMagic(c.magic), "."+string(c.single))
+	}
+	RegisterExtractor(FormatRaw, fileExtractor{}, nil)
+}
+
+// parsesAsTar reports whether src, decompressed with open, starts with a
+// valid tar header. Unlike the magic check, this accepts old v7 tarballs,
+// whose headers have no "ustar" magic but still carry a checksum.
+func parsesAsTar(src string, open decompressor) bool {
+	f, err := os.Open(src)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	dr, err := open(f)
+	if err != nil {
+		return false
+	}
+	defer dr.Close()
+	_, err = tar.NewReader(dr).Next()
+	return err == nil
+}
+
+// tarballOf returns the compressed tarball format of the single compressed
+// file format single, with its decompressor
+func tarballOf(single Format) (Format, decompressor, bool) {
+	for _, c := range compressions {
+		if c.single == single {
+			return c.tar, c.open, true
+		}
+	}
+	return "", nil, false
+}
+
+// AssetFormat returns the format of the asset at path, named name. Magic
+// bytes win over the name, so a misnamed archive isn't installed as the
+// binary; the name covers formats without a signature, such as tarballs
+// without a ustar header.
+func AssetFormat(path, name string) (Format, error) {
+	format, err := DetectFormat(path)
+	if err != nil {
+		return FormatRaw, err
+	}
+	named := FormatFromName(name)
+	if format == FormatRaw {
+		return named, nil
+	}
+	// A compressed tarball is only detected by the ustar magic inside, so
+	// trust a name saying tarball if the content parses as one
+	if tarball, open, ok := tarballOf(format); ok && named == tarball && parsesAsTar(path, open) {
+		return tarball, nil
+	}
+	return format, nil
+}
+
+// sniffLen covers a tar header, whose "ustar" magic sits at offset 257
+const sniffLen = 512
+
+// hasMagic matches content starting with one of the signatures
+func hasMagic(signatures ...[]byte) func(io.Reader) bool {
+	return func(r io.Reader) bool {
+		head, err := readHead(r)
+		if err != nil {
+			return false
+		}
+		for _, magic := range signatures {
+			if bytes.HasPrefix(head, magic) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// isTar matches content starting with a POSIX or GNU tar header
+func isTar(r io.Reader) bool {
+	head, err := readHead(r)
+	return err == nil && len(head) >= 262 && bytes.Equal(head[257:262], []byte("ustar"))
+}
+
+// compressedTar matches tarballs compressed with c, peeking into the
+// decompressed stream
+func compressedTar(c compression) func(io.Reader) bool {
+	return func(r io.Reader) bool {
+		head := make([]byte, len(c.magic))
+		if _, err := io.ReadFull(r, head); err != nil || !bytes.Equal(head, c.magic) {
+			return false
+		}
+		dr, err := c.open(io.MultiReader(bytes.NewReader(head), r))
+		if err != nil {
+			return false
+		}
+		defer dr.Close()
+		return isTar(dr)
+	}
+}
+
+// readHead reads up to sniffLen bytes; shorter content is returned whole
+func readHead(r io.Reader) ([]byte, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	return head[:n], nil
+}
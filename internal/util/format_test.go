@@ -0,0 +1,232 @@
+package util
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// v7Tar returns a tarball of one file whose header lacks the ustar magic, as
+// written by old tar implementations
+func v7Tar(t *testing.T, name, body string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	hdr := &tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o755, Size: int64(len(body)), Format: tar.FormatUSTAR}
+	if err := tw.WriteHeader(hdr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(body)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	header := data[:512]
+	clear(header[257:265]) // magic and version
+	copy(header[148:156], "        ")
+	sum := 0
+	for _, b := range header {
+		sum += int(b)
+	}
+	copy(header[148:156], fmt.Sprintf("%06o\x00 ", sum))
+	return data
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestAssetFormat(t *testing.T) {
+	v7 := gzipped(t, v7Tar(t, "tool", "binary"))
+	tests := []struct {
+		name  string
+		asset string
+		data  []byte
+		want  Format
+	}{
+		{"v7 tarball name", "tool.tar.gz", v7, FormatTarGz},
+		{"v7 tgz name", "tool.tgz", v7, FormatTarGz},
+		// Without a name saying tarball, a gzip without the ustar magic is a single file
+		{"v7 gz name", "tool.gz", v7, FormatGz},
+		// A single compressed binary misnamed as a tarball stays a single file
+		{"gzipped binary named tarball", "tool.tar.gz", gzipped(t, []byte("\x7fELF binary")), FormatGz},
+		// Magic bytes win over the name
+		{"zip named tarball", "tool.tar.gz", zipped(t), FormatZip},
+		{"raw binary", "tool", []byte("\x7fELF"), FormatRaw},
+		{"unsigned format by name", "tool.tar", v7Tar(t, "tool", "binary"), FormatTar},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := filepath.Join(t.TempDir(), tt.asset)
+			if err := os.WriteFile(src, tt.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := AssetFormat(src, tt.asset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("AssetFormat() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// bzip2Hello is bzip2("hello\n"); the standard library has no bzip2 writer
+const bzip2Hello = "425a6839314159265359c1c080e2000001410000100244a00030cd00c3462997177245385090c1c080e2"
+
+func ustarTar(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "tool", Typeflag: tar.TypeReg, Mode: 0o755, Size: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte("tool")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func xzed(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := xz.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zstded(t *testing.T, data []byte) []byte {
+	t.Helper()
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer enc.Close()
+	return enc.EncodeAll(data, nil)
+}
+
+func zipped(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("tool")); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectFormat(t *testing.T) {
+	tarball := ustarTar(t)
+	bz2, err := hex.DecodeString(bzip2Hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want Format
+	}{
+		{"zip", zipped(t), FormatZip},
+		{"empty zip", []byte("PK\x05\x06" + string(make([]byte, 18))), FormatZip},
+		{"tar", tarball, FormatTar},
+		{"tar.gz", gzipped(t, tarball), FormatTarGz},
+		{"tar.xz", xzed(t, tarball), FormatTarXz},
+		{"tar.zst", zstded(t, tarball), FormatTarZst},
+		{"gz", gzipped(t, []byte("\x7fELF")), FormatGz},
+		{"xz", xzed(t, []byte("\x7fELF")), FormatXz},
+		{"zst", zstded(t, []byte("\x7fELF")), FormatZst},
+		{"bz2", bz2, FormatBz2},
+		// Old tarballs lack the ustar magic; Extract falls back to the name
+		{"v7 tar.gz", gzipped(t, v7Tar(t, "tool", "x")), FormatGz},
+		{"elf", []byte("\x7fELF\x02\x01\x01"), FormatRaw},
+		{"script", []byte("#!/bin/sh\necho hi\n"), FormatRaw},
+		{"empty", nil, FormatRaw},
+		{"truncated gzip", []byte{0x1f, 0x8b}, FormatGz},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "asset")
+			if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := DetectFormat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("DetectFormat() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatFromName(t *testing.T) {
+	tests := []struct {
+		name string
+		want Format
+	}{
+		{"tool-linux-amd64.tar.gz", FormatTarGz},
+		{"tool.tgz", FormatTarGz},
+		{"tool.tar.xz", FormatTarXz},
+		{"tool.txz", FormatTarXz},
+		{"tool.tar.zst", FormatTarZst},
+		{"tool.tzst", FormatTarZst},
+		{"tool.tar.bz2", FormatTarBz2},
+		{"tool.tbz", FormatTarBz2},
+		{"tool.tar", FormatTar},
+		{"TOOL.ZIP", FormatZip},
+		{"tool.gz", FormatGz},
+		{"tool.xz", FormatXz},
+		{"tool.zst", FormatZst},
+		{"tool.bz2", FormatBz2},
+		{"tool-linux-amd64", FormatRaw},
+		{"tool.exe", FormatRaw},
+		{"tool.tar.gz.sig", FormatRaw},
+	}
+	for _, tt := range tests {
+		if got := FormatFromName(tt.name); got != tt.want {
+			t.Errorf("FormatFromName(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -21,6 +21,17 @@ const (
 	Aarch64 Arch = "aarch64"
 )
 
+// UnsupportedPlatformError is returned by GetSystemInfo on an OS or CPU
+// architecture that has no Platform or Arch
+type UnsupportedPlatformError struct {
+	GOOS   string
+	GOARCH string
+}
+
+func (e *UnsupportedPlatformError) Error() string {
+	return fmt.Sprintf("unsupported platform: %s/%s", e.GOOS, e.GOARCH)
+}
+
 // GetSystemInfo returns the current platform and architecture
 func GetSystemInfo() (Platform, Arch, error) {
 	var p Platform
@@ -30,7 +41,7 @@ func GetSystemInfo() (Platform, Arch, error) {
 	case "linux":
 		p = Linux
 	default:
-		return "", "", fmt.Errorf("unsupported platform: %s", runtime.GOOS)
+		return "", "", &UnsupportedPlatformError{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
 	}
 
 	var a Arch
@@ -40,7 +51,7 @@ func GetSystemInfo() (Platform, Arch, error) {
 	case "arm64":
 		a = Aarch64
 	default:
-		return "", "", fmt.Errorf("unsupported architecture: %s", runtime.GOARCH)
+		return "", "", &UnsupportedPlatformError{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
 	}
 
 	return p, a, nil
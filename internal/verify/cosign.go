@@ -0,0 +1,256 @@
+package verify
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sigstoreBundle is the subset of the Sigstore bundle format (v0.1-v0.3)
+// needed for offline verification. RFC 3161 timestamps are ignored.
+type sigstoreBundle struct {
+	MediaType            string `json:"mediaType"`
+	VerificationMaterial struct {
+		Certificate *struct {
+			RawBytes []byte `json:"rawBytes"`
+		} `json:"certificate"`
+		X509CertificateChain *struct {
+			Certificates []struct {
+				RawBytes []byte `json:"rawBytes"`
+			} `json:"certificates"`
+		} `json:"x509CertificateChain"`
+		TlogEntries []tlogEntry `json:"tlogEntries"`
+	} `json:"verificationMaterial"`
+	MessageSignature *struct {
+		MessageDigest struct {
+			Algorithm string `json:"algorithm"`
+			Digest    []byte `json:"digest"`
+		} `json:"messageDigest"`
+		Signature []byte `json:"signature"`
+	} `json:"messageSignature"`
+	DSSEEnvelope *struct {
+		Payload     []byte `json:"payload"`
+		PayloadType string `json:"payloadType"`
+		Signatures  []struct {
+			Sig []byte `json:"sig"`
+		} `json:"signatures"`
+	} `json:"dsseEnvelope"`
+}
+
+// legacyBundle is the bundle written by `cosign sign-blob --bundle` before v2.4
+type legacyBundle struct {
+	Base64Signature string `json:"base64Signature"`
+	Cert            string `json:"cert"` // base64-encoded PEM
+	RekorBundle     *struct {
+		SignedEntryTimestamp []byte `json:"SignedEntryTimestamp"`
+		Payload              struct {
+			Body           []byte `json:"body"`
+			IntegratedTime int64  `json:"integratedTime"`
+			LogIndex       int64  `json:"logIndex"`
+			LogID          string `json:"logID"` // Hex
+		} `json:"Payload"`
+	} `json:"rekorBundle"`
+}
+
+// inTotoStatement is the payload of an attestation
+type inTotoStatement struct {
+	Subject []struct {
+		Name   string            `json:"name"`
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+}
+
+// Cosign verifies an asset whose SHA-256 is digest against a cosign signature.
+// sigData may be a raw base64 signature, a legacy cosign bundle or a Sigstore bundle.
+func Cosign(digest, sigData []byte, policy Policy) error {
+	trimmed := bytes.TrimSpace(sigData)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var probe struct {
+			MediaType       string `json:"mediaType"`
+			Base64Signature string `json:"base64Signature"`
+		}
+		if err := json.Unmarshal(trimmed, &probe); err != nil {
+			return invalidf("malformed bundle: %v", err)
+		}
+		if probe.MediaType != "" {
+			return Bundle(digest, trimmed, policy)
+		}
+		return verifyLegacyBundle(digest, trimmed, policy)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(string(trimmed))
+	if err != nil {
+		return invalidf("malformed signature: %v", err)
+	}
+	if policy.PublicKey == nil {
+		return errors.New("raw cosign signature requires a public key")
+	}
+	return verifyDigest(policy.PublicKey, digest, sig)
+}
+
+// Bundle verifies a Sigstore bundle, either a message signature over the
+// asset or a DSSE attestation whose subject includes the asset digest.
+func Bundle(digest, data []byte, policy Policy) error {
+	var b sigstoreBundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return invalidf("malformed bundle: %v", err)
+	}
+	if !strings.HasPrefix(b.MediaType, "application/vnd.dev.sigstore.bundle") {
+		return invalidf("unsupported bundle media type %q", b.MediaType)
+	}
+
+	var certDER []byte
+	if c := b.VerificationMaterial.Certificate; c != nil {
+		certDER = c.RawBytes
+	} else if chain := b.VerificationMaterial.X509CertificateChain; chain != nil && len(chain.Certificates) > 0 {
+		certDER = chain.Certificates[0].RawBytes
+	}
+	pub, cert, err := policy.signingKey(certDER)
+	if err != nil {
+		return err
+	}
+
+	var signed loggedSignature
+	var statement []byte
+	switch {
+	case b.MessageSignature != nil:
+		ms := b.MessageSignature
+		if len(ms.MessageDigest.Digest) > 0 && !bytes.Equal(ms.MessageDigest.Digest, digest) {
+			return invalidf("bundle is for a different artifact")
+		}
+		if err := verifyDigest(pub, digest, ms.Signature); err != nil {
+			return err
+		}
+		signed = loggedSignature{sig: ms.Signature, hash: digest}
+
+	case b.DSSEEnvelope != nil:
+		env := b.DSSEEnvelope
+		pae := dssePAE(env.PayloadType, env.Payload)
+		verified := false
+		for _, s := range env.Signatures {
+			if verifyMessage(pub, pae, s.Sig) == nil {
+				payloadHash := sha256.Sum256(env.Payload)
+				signed = loggedSignature{sig: s.Sig, hash: payloadHash[:]}
+				verified = true
+				break
+			}
+		}
+		if !verified {
+			return invalidf("dsse signature mismatch")
+		}
+		statement = env.Payload
+
+	default:
+		return invalidf("bundle has no signature")
+	}
+
+	if cert != nil {
+		signed.cert = cert
+		if err := policy.verifyCertificate(b.VerificationMaterial.TlogEntries, signed); err != nil {
+			return err
+		}
+	}
+	if statement == nil {
+		return nil
+	}
+
+	var stmt inTotoStatement
+	if err := json.Unmarshal(statement, &stmt); err != nil {
+		return invalidf("malformed attestation payload: %v", err)
+	}
+	want := hex.EncodeToString(digest)
+	for _, subject := range stmt.Subject {
+		if subject.Digest["sha256"] == want {
+			return nil
+		}
+	}
+	return invalidf("attestation does not cover sha256:%s", want)
+}
+
+func verifyLegacyBundle(digest, data []byte, policy Policy) error {
+	var b legacyBundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return invalidf("malformed bundle: %v", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(b.Base64Signature)
+	if err != nil {
+		return invalidf("malformed signature: %v", err)
+	}
+
+	var certDER []byte
+	if b.Cert != "" {
+		certPEM, err := base64.StdEncoding.DecodeString(b.Cert)
+		if err != nil {
+			return invalidf("malformed certificate: %v", err)
+		}
+		block, _ := pem.Decode(certPEM)
+		if block == nil {
+			return invalidf("malformed certificate")
+		}
+		certDER = block.Bytes
+	}
+
+	pub, cert, err := policy.signingKey(certDER)
+	if err != nil {
+		return err
+	}
+	if err := verifyDigest(pub, digest, sig); err != nil {
+		return err
+	}
+	if cert == nil {
+		return nil
+	}
+
+	// The Rekor bundle holds a signed entry timestamp but no inclusion proof
+	var entries []tlogEntry
+	if rb := b.RekorBundle; rb != nil {
+		logID, err := hex.DecodeString(rb.Payload.LogID)
+		if err != nil {
+			return invalidf("malformed rekor bundle log ID: %v", err)
+		}
+		e := tlogEntry{LogIndex: rb.Payload.LogIndex, IntegratedTime: rb.Payload.IntegratedTime, CanonicalizedBody: rb.Payload.Body}
+		e.LogID.KeyID = logID
+		e.InclusionPromise = &inclusionPromise{SignedEntryTimestamp: rb.SignedEntryTimestamp}
+		entries = append(entries, e)
+	}
+	return policy.verifyCertificate(entries, loggedSignature{sig: sig, cert: cert, hash: digest})
+}
+
+// signingKey returns the configured public key, or the key of the signing
+// certificate along with the certificate, which the caller must verify with
+// verifyCertificate once the signature checks out.
+func (p Policy) signingKey(certDER []byte) (crypto.PublicKey, *x509.Certificate, error) {
+	if p.PublicKey != nil {
+		return p.PublicKey, nil, nil
+	}
+	if len(certDER) == 0 {
+		return nil, nil, invalidf("no signing certificate in bundle")
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, nil, invalidf("malformed certificate: %v", err)
+	}
+	return cert.PublicKey, cert, nil
+}
+
+// dssePAE returns the DSSE pre-authentication encoding
+func dssePAE(payloadType string, payload []byte) []byte {
+	return fmt.Appendf(nil, "DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload)
+}
+
+// SHA256 is a convenience for callers holding the hex digest of an asset
+func SHA256(hexDigest string) ([]byte, error) {
+	b, err := hex.DecodeString(hexDigest)
+	if err != nil || len(b) != sha256.Size {
+		return nil, fmt.Errorf("invalid sha256 digest %q", hexDigest)
+	}
+	return b, nil
+}
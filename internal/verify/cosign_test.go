@@ -0,0 +1,410 @@
+package verify
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const (
+	testIdentity = "https://github.com/owner/repo/.github/workflows/release.yml@refs/tags/v1.0.0"
+	testIssuer   = "https://token.actions.githubusercontent.com"
+	bundleType   = "application/vnd.dev.sigstore.bundle.v0.3+json"
+	inTotoType   = "application/vnd.in-toto+json"
+)
+
+// errAny marks an expected failure that is not ErrSignatureInvalid
+var errAny = errors.New("any error")
+
+// keyless is a test Sigstore instance: a CA with a Fulcio-style signing
+// certificate it issued, a certificate transparency log that signed a
+// timestamp for it and a Rekor log that records signatures
+type keyless struct {
+	root   *TrustedRoot
+	caCert *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	ctKey  *ecdsa.PrivateKey
+	rekor  *ecdsa.PrivateKey
+	cert   []byte // DER
+	key    *ecdsa.PrivateKey
+}
+
+func newKeyless(t *testing.T) keyless {
+	t.Helper()
+	k := keyless{caKey: newECKey(t), ctKey: newECKey(t), rekor: newECKey(t), key: newECKey(t)}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &k.caKey.PublicKey, k.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.caCert, _ = x509.ParseCertificate(caDER)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(k.caCert)
+	k.root = &TrustedRoot{
+		authorities: []certAuthority{{roots: roots, intermediates: x509.NewCertPool()}},
+		tlogs:       map[string]logKey{string(keyID(t, &k.rekor.PublicKey)): {key: &k.rekor.PublicKey}},
+		ctlogs:      map[string]logKey{string(keyID(t, &k.ctKey.PublicKey)): {key: &k.ctKey.PublicKey}},
+	}
+	k.cert = k.issue(t, true)
+	return k
+}
+
+// issue returns a signing certificate for k.key, with an embedded SCT if sct
+func (k keyless) issue(t *testing.T, sct bool) []byte {
+	t.Helper()
+	issuer, err := asn1.Marshal(testIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := url.Parse(testIdentity)
+	leaf := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       time.Now().Add(-time.Minute),
+		NotAfter:        time.Now().Add(10 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		URIs:            []*url.URL{id},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuer}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, leaf, k.caCert, &k.key.PublicKey, k.caKey)
+	if err != nil || !sct {
+		return der
+	}
+
+	// Like Fulcio, have the log sign the precertificate, then embed its SCT
+	precert, _ := x509.ParseCertificate(der)
+	leaf.ExtraExtensions = append(leaf.ExtraExtensions, pkix.Extension{Id: oidSCTList, Value: k.sctList(t, precert)})
+	if der, err = x509.CreateCertificate(rand.Reader, leaf, k.caCert, &k.key.PublicKey, k.caKey); err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// sctList returns the SCT list extension value for precert
+func (k keyless) sctList(t *testing.T, precert *x509.Certificate) []byte {
+	t.Helper()
+	timestamp := uint64(time.Now().UnixMilli())
+	issuerKeyHash := sha256.Sum256(k.caCert.RawSubjectPublicKeyInfo)
+	var signed cryptobyte.Builder
+	signed.AddUint8(0)
+	signed.AddUint8(0)
+	signed.AddUint64(timestamp)
+	signed.AddUint16(1)
+	signed.AddBytes(issuerKeyHash[:])
+	signed.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(precert.RawTBSCertificate) })
+	signed.AddUint16(0)
+	digest := sha256.Sum256(signed.BytesOrPanic())
+
+	var list cryptobyte.Builder
+	list.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint8(0)
+			b.AddBytes(keyID(t, &k.ctKey.PublicKey))
+			b.AddUint64(timestamp)
+			b.AddUint16(0)
+			b.AddUint8(4) // SHA-256
+			b.AddUint8(3) // ECDSA
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(signDigest(t, k.ctKey, digest[:])) })
+		})
+	})
+	value, err := asn1.Marshal(list.BytesOrPanic())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+// keyID returns the log ID of a log with key
+func keyID(t *testing.T, key *ecdsa.PublicKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := sha256.Sum256(der)
+	return id[:]
+}
+
+func newECKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func signDigest(t *testing.T, key *ecdsa.PrivateKey, digest []byte) []byte {
+	t.Helper()
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func marshal(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// certPEM returns the signing certificate as Rekor records it
+func (k keyless) certPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: k.cert})
+}
+
+// hashedrekord returns the Rekor entry body of a signature over digest
+func (k keyless) hashedrekord(t *testing.T, sig, digest []byte) []byte {
+	t.Helper()
+	return marshal(t, map[string]any{
+		"apiVersion": "0.0.1",
+		"kind":       "hashedrekord",
+		"spec": map[string]any{
+			"data":      map[string]any{"hash": map[string]any{"algorithm": "sha256", "value": hex.EncodeToString(digest)}},
+			"signature": map[string]any{"content": sig, "publicKey": map[string]any{"content": k.certPEM()}},
+		},
+	})
+}
+
+// set returns the signed entry timestamp of a Rekor entry
+func (k keyless) set(t *testing.T, body []byte, integrated time.Time, logIndex int64) []byte {
+	t.Helper()
+	payload := marshal(t, map[string]any{
+		"body":           body,
+		"integratedTime": integrated.Unix(),
+		"logID":          hex.EncodeToString(keyID(t, &k.rekor.PublicKey)),
+		"logIndex":       logIndex,
+	})
+	digest := sha256.Sum256(payload)
+	return signDigest(t, k.rekor, digest[:])
+}
+
+// tlogEntry returns a bundle's log entry for body, integrated at integrated,
+// with a signed entry timestamp
+func (k keyless) tlogEntry(t *testing.T, body []byte, integrated time.Time) map[string]any {
+	t.Helper()
+	return map[string]any{
+		"logIndex":          "42",
+		"logId":             map[string]any{"keyId": keyID(t, &k.rekor.PublicKey)},
+		"kindVersion":       map[string]any{"kind": "hashedrekord", "version": "0.0.1"},
+		"integratedTime":    strconv.FormatInt(integrated.Unix(), 10),
+		"inclusionPromise":  map[string]any{"signedEntryTimestamp": k.set(t, body, integrated, 42)},
+		"canonicalizedBody": body,
+	}
+}
+
+// messageBundle returns a Sigstore bundle signing digest with k
+func (k keyless) messageBundle(t *testing.T, digest []byte) []byte {
+	t.Helper()
+	sig := signDigest(t, k.key, digest)
+	return marshal(t, map[string]any{
+		"mediaType": bundleType,
+		"verificationMaterial": map[string]any{
+			"certificate": map[string]any{"rawBytes": k.cert},
+			"tlogEntries": []any{k.tlogEntry(t, k.hashedrekord(t, sig, digest), time.Now())},
+		},
+		"messageSignature": map[string]any{
+			"messageDigest": map[string]any{"algorithm": "SHA2_256", "digest": digest},
+			"signature":     sig,
+		},
+	})
+}
+
+// dsseBundle returns a Sigstore bundle attesting subjectDigest with k
+func (k keyless) dsseBundle(t *testing.T, subjectDigest []byte) []byte {
+	t.Helper()
+	payload := marshal(t, map[string]any{
+		"_type":         "https://in-toto.io/Statement/v1",
+		"subject":       []any{map[string]any{"name": "tool.tar.gz", "digest": map[string]string{"sha256": hex.EncodeToString(subjectDigest)}}},
+		"predicateType": "https://slsa.dev/provenance/v1",
+	})
+	pae := sha256.Sum256(dssePAE(inTotoType, payload))
+	sig := signDigest(t, k.key, pae[:])
+	payloadHash := sha256.Sum256(payload)
+	body := marshal(t, map[string]any{
+		"apiVersion": "0.0.1",
+		"kind":       "dsse",
+		"spec": map[string]any{
+			"payloadHash": map[string]any{"algorithm": "sha256", "value": hex.EncodeToString(payloadHash[:])},
+			"signatures":  []any{map[string]any{"signature": sig, "verifier": k.certPEM()}},
+		},
+	})
+	return marshal(t, map[string]any{
+		"mediaType": bundleType,
+		"verificationMaterial": map[string]any{
+			"certificate": map[string]any{"rawBytes": k.cert},
+			"tlogEntries": []any{k.tlogEntry(t, body, time.Now())},
+		},
+		"dsseEnvelope": map[string]any{
+			"payload":     payload,
+			"payloadType": inTotoType,
+			"signatures":  []any{map[string]any{"sig": sig}},
+		},
+	})
+}
+
+// withEntries replaces the log entries of a bundle
+func withEntries(t *testing.T, bundle []byte, entries ...any) []byte {
+	t.Helper()
+	var b map[string]any
+	if err := json.Unmarshal(bundle, &b); err != nil {
+		t.Fatal(err)
+	}
+	b["verificationMaterial"].(map[string]any)["tlogEntries"] = entries
+	return marshal(t, b)
+}
+
+// legacyBundle returns a cosign sign-blob bundle signing digest with k
+func (k keyless) legacyBundle(t *testing.T, digest []byte, integrated time.Time) []byte {
+	t.Helper()
+	sig := signDigest(t, k.key, digest)
+	body := k.hashedrekord(t, sig, digest)
+	return marshal(t, map[string]any{
+		"base64Signature": base64.StdEncoding.EncodeToString(sig),
+		"cert":            base64.StdEncoding.EncodeToString(k.certPEM()),
+		"rekorBundle": map[string]any{
+			"SignedEntryTimestamp": k.set(t, body, integrated, 42),
+			"Payload": map[string]any{
+				"body":           body,
+				"integratedTime": integrated.Unix(),
+				"logIndex":       42,
+				"logID":          hex.EncodeToString(keyID(t, &k.rekor.PublicKey)),
+			},
+		},
+	})
+}
+
+func TestCosign(t *testing.T) {
+	digest := sha256.Sum256([]byte("release asset"))
+	other := sha256.Sum256([]byte("other asset"))
+	key, otherKey := newECKey(t), newECKey(t)
+	k := newKeyless(t)
+
+	keyPolicy := Policy{PublicKey: &key.PublicKey}
+	keylessPolicy := Policy{Root: k.root, Identity: testIdentity, Issuer: testIssuer}
+
+	raw := []byte(base64.StdEncoding.EncodeToString(signDigest(t, key, digest[:])))
+
+	// A second instance whose log and CA the root doesn't trust
+	untrusted := newKeyless(t)
+	untrusted.key = k.key
+	untrusted.cert = k.cert
+	noSCT := k
+	noSCT.cert = k.issue(t, false)
+	otherCA := *k.root
+	otherCA.authorities = newKeyless(t).root.authorities
+
+	tests := []struct {
+		name    string
+		sig     []byte
+		policy  Policy
+		wantErr error // nil for success, ErrSignatureInvalid, or errAny
+	}{
+		{"raw signature", raw, keyPolicy, nil},
+		{"raw signature, other key", raw, Policy{PublicKey: &otherKey.PublicKey}, ErrSignatureInvalid},
+		{"raw signature without key", raw, keylessPolicy, errAny},
+		{"raw signature not base64", []byte("not base64!"), keyPolicy, ErrSignatureInvalid},
+		{"legacy bundle", k.legacyBundle(t, digest[:], time.Now()), keylessPolicy, nil},
+		{"legacy bundle without rekor bundle", marshal(t, map[string]string{
+			"base64Signature": base64.StdEncoding.EncodeToString(signDigest(t, k.key, digest[:])),
+			"cert":            base64.StdEncoding.EncodeToString(k.certPEM()),
+		}), keylessPolicy, ErrSignatureInvalid},
+		{"legacy bundle signed after the certificate expired", k.legacyBundle(t, digest[:], time.Now().Add(time.Hour)), keylessPolicy, ErrSignatureInvalid},
+		{"message bundle", k.messageBundle(t, digest[:]), keylessPolicy, nil},
+		{"message bundle with key", k.messageBundle(t, digest[:]), Policy{PublicKey: &k.key.PublicKey}, nil},
+		{"message bundle for other artifact", k.messageBundle(t, other[:]), keylessPolicy, ErrSignatureInvalid},
+		{"identity regexp", k.messageBundle(t, digest[:]), Policy{Root: k.root, IdentityRegexp: regexp.MustCompile(`^https://github\.com/owner/`)}, nil},
+		{"other identity", k.messageBundle(t, digest[:]), Policy{Root: k.root, Identity: "https://github.com/evil/repo"}, ErrSignatureInvalid},
+		{"no identity", k.messageBundle(t, digest[:]), Policy{Root: k.root}, ErrSignatureInvalid},
+		{"other issuer", k.messageBundle(t, digest[:]), Policy{Root: k.root, Identity: testIdentity, Issuer: "https://accounts.google.com"}, ErrSignatureInvalid},
+		{"untrusted root", k.messageBundle(t, digest[:]), Policy{Root: newKeyless(t).root, Identity: testIdentity}, ErrSignatureInvalid},
+		{"untrusted CA", k.messageBundle(t, digest[:]), Policy{Root: &otherCA, Identity: testIdentity}, ErrSignatureInvalid},
+		{"no root", k.messageBundle(t, digest[:]), Policy{Identity: testIdentity}, errAny},
+		{"no log entry", withEntries(t, k.messageBundle(t, digest[:])), keylessPolicy, ErrSignatureInvalid},
+		{"entry in an untrusted log", untrusted.messageBundle(t, digest[:]), keylessPolicy, ErrSignatureInvalid},
+		{"entry for another signature", withEntries(t, k.messageBundle(t, digest[:]),
+			k.tlogEntry(t, k.hashedrekord(t, signDigest(t, k.key, digest[:]), digest[:]), time.Now())), keylessPolicy, ErrSignatureInvalid},
+		{"entry for another artifact", withEntries(t, k.messageBundle(t, digest[:]),
+			k.tlogEntry(t, k.hashedrekord(t, signDigest(t, k.key, other[:]), other[:]), time.Now())), keylessPolicy, ErrSignatureInvalid},
+		{"certificate without SCT", noSCT.messageBundle(t, digest[:]), keylessPolicy, ErrSignatureInvalid},
+		{"dsse attestation", k.dsseBundle(t, digest[:]), keylessPolicy, nil},
+		{"dsse attestation of other artifact", k.dsseBundle(t, other[:]), keylessPolicy, ErrSignatureInvalid},
+		{"dsse signed by other key", k.dsseBundle(t, digest[:]), Policy{PublicKey: &otherKey.PublicKey}, ErrSignatureInvalid},
+		{"unsupported media type", []byte(`{"mediaType":"application/json"}`), keylessPolicy, ErrSignatureInvalid},
+		{"bundle without signature", marshal(t, map[string]any{
+			"mediaType":            bundleType,
+			"verificationMaterial": map[string]any{"certificate": map[string]any{"rawBytes": k.cert}},
+		}), keylessPolicy, ErrSignatureInvalid},
+		{"malformed bundle", []byte(`{"mediaType":`), keylessPolicy, ErrSignatureInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Cosign(digest[:], tt.sig, tt.policy)
+			switch tt.wantErr {
+			case nil:
+				if err != nil {
+					t.Fatalf("Cosign() error = %v", err)
+				}
+			case errAny:
+				if err == nil || errors.Is(err, ErrSignatureInvalid) {
+					t.Errorf("Cosign() error = %v, want a configuration error", err)
+				}
+			default:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Cosign() error = %v, want %v", err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestTamperedDSSEPayload(t *testing.T) {
+	digest := sha256.Sum256([]byte("release asset"))
+	k := newKeyless(t)
+
+	var b map[string]any
+	if err := json.Unmarshal(k.dsseBundle(t, sha256.New().Sum(nil)), &b); err != nil {
+		t.Fatal(err)
+	}
+	// Point the signed statement at the asset after signing
+	env := b["dsseEnvelope"].(map[string]any)
+	env["payload"] = marshal(t, map[string]any{
+		"subject": []any{map[string]any{"digest": map[string]string{"sha256": hex.EncodeToString(digest[:])}}},
+	})
+
+	err := Bundle(digest[:], marshal(t, b), Policy{Root: k.root, Identity: testIdentity})
+	if !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("Bundle() error = %v, want %v", err, ErrSignatureInvalid)
+	}
+}
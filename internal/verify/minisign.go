@@ -0,0 +1,78 @@
+package verify
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Minisign verifies file against a .minisig signature using a minisign public
+// key in its base64 form (the second line of a minisign.pub file).
+func Minisign(file string, sigData []byte, publicKey string) error {
+	keyBytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(keyBytes) != 2+8+ed25519.PublicKeySize || string(keyBytes[:2]) != "Ed" {
+		return errors.New("invalid minisign public key")
+	}
+	keyID := keyBytes[2:10]
+	pub := ed25519.PublicKey(keyBytes[10:])
+
+	lines := strings.Split(strings.ReplaceAll(string(sigData), "\r\n", "\n"), "\n")
+	if len(lines) < 4 {
+		return invalidf("malformed minisign signature")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return invalidf("malformed minisign signature")
+	}
+	algorithm, sigKeyID, signature := string(sig[:2]), sig[2:10], sig[10:]
+	if !bytes.Equal(sigKeyID, keyID) {
+		return invalidf("signed with key %X, expected %X", sigKeyID, keyID)
+	}
+
+	var msg []byte
+	switch algorithm {
+	case "ED":
+		// Prehashed: the signature covers the BLAKE2b-512 of the file
+		h, _ := blake2b.New512(nil)
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		msg = h.Sum(nil)
+	case "Ed":
+		if msg, err = os.ReadFile(file); err != nil {
+			return err
+		}
+	default:
+		return invalidf("unsupported minisign algorithm %q", algorithm)
+	}
+
+	if !ed25519.Verify(pub, msg, signature) {
+		return invalidf("minisign signature mismatch")
+	}
+
+	// The global signature binds the trusted comment to the signature
+	trusted, ok := strings.CutPrefix(lines[2], "trusted comment: ")
+	if !ok {
+		return invalidf("missing trusted comment")
+	}
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return invalidf("malformed global signature")
+	}
+	if !ed25519.Verify(pub, append(bytes.Clone(signature), trusted...), global) {
+		return invalidf("minisign trusted comment signature mismatch")
+	}
+	return nil
+}
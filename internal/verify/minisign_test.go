@@ -0,0 +1,98 @@
+package verify
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// minisignKey is a test key pair in minisign's encoding
+type minisignKey struct {
+	id   []byte
+	pub  ed25519.PublicKey
+	priv ed25519.PrivateKey
+}
+
+func newMinisignKey(t *testing.T) minisignKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+	return minisignKey{id: id, pub: pub, priv: priv}
+}
+
+// publicKey returns the base64 line of a minisign.pub file
+func (k minisignKey) publicKey() string {
+	return base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), k.id...), k.pub...))
+}
+
+// sign returns a .minisig for data; prehashed selects the ED algorithm
+func (k minisignKey) sign(data []byte, prehashed bool, trusted string) []byte {
+	algorithm, msg := "Ed", data
+	if prehashed {
+		sum := blake2b.Sum512(data)
+		algorithm, msg = "ED", sum[:]
+	}
+	sig := ed25519.Sign(k.priv, msg)
+	global := ed25519.Sign(k.priv, append(append([]byte{}, sig...), trusted...))
+	line := append(append([]byte(algorithm), k.id...), sig...)
+	return fmt.Appendf(nil, "untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(line), trusted, base64.StdEncoding.EncodeToString(global))
+}
+
+func TestMinisign(t *testing.T) {
+	key, other := newMinisignKey(t), newMinisignKey(t)
+	data := []byte("release asset")
+	file := filepath.Join(t.TempDir(), "asset")
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The trusted comment changed without re-signing it
+	tampered := bytes.Replace(key.sign(data, true, "timestamp:1"), []byte("timestamp:1"), []byte("timestamp:2"), 1)
+
+	tests := []struct {
+		name      string
+		sig       []byte
+		publicKey string
+		invalid   bool // Fails with ErrSignatureInvalid rather than another error
+	}{
+		{"prehashed", key.sign(data, true, "timestamp:1"), key.publicKey(), false},
+		{"legacy", key.sign(data, false, "timestamp:1"), key.publicKey(), false},
+		{"other data", key.sign([]byte("other asset"), true, "timestamp:1"), key.publicKey(), true},
+		{"other key", other.sign(data, true, "timestamp:1"), key.publicKey(), true},
+		{"trusted comment changed", tampered, key.publicKey(), true},
+		{"truncated", []byte("untrusted comment: x\n"), key.publicKey(), true},
+		{"not base64", []byte("untrusted comment: x\n!!!\ntrusted comment: y\n!!!\n"), key.publicKey(), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Minisign(file, tt.sig, tt.publicKey)
+			if !tt.invalid {
+				if err != nil {
+					t.Fatalf("Minisign() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrSignatureInvalid) {
+				t.Errorf("Minisign() error = %v, want %v", err, ErrSignatureInvalid)
+			}
+		})
+	}
+
+	if err := Minisign(file, key.sign(data, true, "t"), "bm90IGEga2V5"); err == nil || errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("Minisign() with a malformed public key error = %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package verify
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	cbasn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// oidSCTList is the extension embedding signed certificate timestamps (RFC 6962)
+var oidSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+
+// verifySCT checks that cert embeds a signed certificate timestamp (SCT) from
+// a certificate transparency log of the root, so the CA can't have issued it
+// without publishing it
+func (r *TrustedRoot) verifySCT(cert, issuer *x509.Certificate) error {
+	var list []byte
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidSCTList) {
+			if _, err := asn1.Unmarshal(ext.Value, &list); err != nil {
+				return invalidf("malformed SCT list: %v", err)
+			}
+		}
+	}
+	if list == nil {
+		return invalidf("certificate has no signed certificate timestamp")
+	}
+	tbs, ok := precertTBS(cert.RawTBSCertificate)
+	if !ok {
+		return invalidf("malformed certificate")
+	}
+	issuerKeyHash := sha256.Sum256(issuer.RawSubjectPublicKeyInfo)
+
+	s := cryptobyte.String(list)
+	var scts cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&scts) {
+		return invalidf("malformed SCT list")
+	}
+	err := invalidf("certificate has no signed certificate timestamp")
+	for !scts.Empty() {
+		var sct, extensions, sig cryptobyte.String
+		var logID []byte
+		var version, hashAlg, sigAlg uint8
+		var timestamp uint64
+		if !scts.ReadUint16LengthPrefixed(&sct) ||
+			!sct.ReadUint8(&version) ||
+			!sct.ReadBytes(&logID, sha256.Size) ||
+			!sct.ReadUint64(&timestamp) ||
+			!sct.ReadUint16LengthPrefixed(&extensions) ||
+			!sct.ReadUint8(&hashAlg) ||
+			!sct.ReadUint8(&sigAlg) ||
+			!sct.ReadUint16LengthPrefixed(&sig) {
+			return invalidf("malformed SCT")
+		}
+		if version != 0 || hashAlg != 4 { // v1, SHA-256
+			continue
+		}
+
+		key, lookupErr := lookupLog(r.ctlogs, logID, time.UnixMilli(int64(timestamp)))
+		if lookupErr != nil {
+			err = lookupErr
+			continue
+		}
+		// The log signed the certificate as submitted, before the SCT list
+		// was added to it
+		var b cryptobyte.Builder
+		b.AddUint8(0) // v1
+		b.AddUint8(0) // certificate_timestamp
+		b.AddUint64(timestamp)
+		b.AddUint16(1) // precert_entry
+		b.AddBytes(issuerKeyHash[:])
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(tbs) })
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(extensions) })
+		signed, buildErr := b.Bytes()
+		if buildErr != nil {
+			return buildErr
+		}
+		if verifyMessage(key, signed, sig) == nil {
+			return nil
+		}
+		err = invalidf("signed certificate timestamp mismatch")
+	}
+	return err
+}
+
+// precertTBS returns the TBSCertificate without the SCT list extension
+func precertTBS(raw []byte) ([]byte, bool) {
+	input := cryptobyte.String(raw)
+	var tbs cryptobyte.String
+	if !input.ReadASN1(&tbs, cbasn1.SEQUENCE) {
+		return nil, false
+	}
+	extensionsTag := cbasn1.Tag(3).Constructed().ContextSpecific()
+
+	ok := true
+	var b cryptobyte.Builder
+	b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		for !tbs.Empty() {
+			var field cryptobyte.String
+			var tag cbasn1.Tag
+			if !tbs.ReadAnyASN1Element(&field, &tag) {
+				ok = false
+				return
+			}
+			if tag != extensionsTag {
+				b.AddBytes(field)
+				continue
+			}
+
+			var extensions cryptobyte.String
+			if !field.ReadASN1(&field, extensionsTag) || !field.ReadASN1(&extensions, cbasn1.SEQUENCE) {
+				ok = false
+				return
+			}
+			b.AddASN1(extensionsTag, func(b *cryptobyte.Builder) {
+				b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					for !extensions.Empty() {
+						var ext, body cryptobyte.String
+						var id asn1.ObjectIdentifier
+						if !extensions.ReadASN1Element(&ext, cbasn1.SEQUENCE) {
+							ok = false
+							return
+						}
+						body = ext
+						if !body.ReadASN1(&body, cbasn1.SEQUENCE) || !body.ReadASN1ObjectIdentifier(&id) {
+							ok = false
+							return
+						}
+						if !id.Equal(oidSCTList) {
+							b.AddBytes(ext)
+						}
+					}
+				})
+			})
+		}
+	})
+	out, err := b.Bytes()
+	return out, ok && err == nil
+}
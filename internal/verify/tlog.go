@@ -0,0 +1,285 @@
+package verify
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tlogEntry is a Rekor transparency log entry from a Sigstore bundle
+type tlogEntry struct {
+	LogIndex int64 `json:"logIndex,string"`
+	LogID    struct {
+		KeyID []byte `json:"keyId"`
+	} `json:"logId"`
+	IntegratedTime   int64             `json:"integratedTime,string"`
+	InclusionPromise *inclusionPromise `json:"inclusionPromise"`
+	InclusionProof   *struct {
+		LogIndex   int64    `json:"logIndex,string"`
+		RootHash   []byte   `json:"rootHash"`
+		TreeSize   int64    `json:"treeSize,string"`
+		Hashes     [][]byte `json:"hashes"`
+		Checkpoint struct {
+			Envelope string `json:"envelope"`
+		} `json:"checkpoint"`
+	} `json:"inclusionProof"`
+	CanonicalizedBody []byte `json:"canonicalizedBody"`
+}
+
+// inclusionPromise is the log's signed entry timestamp (SET), its promise to
+// include the entry
+type inclusionPromise struct {
+	SignedEntryTimestamp []byte `json:"signedEntryTimestamp"`
+}
+
+// loggedSignature is what a log entry must record: the signature, the
+// certificate it was made with, and the SHA-256 of what was signed (the
+// artifact, or the payload of a DSSE envelope)
+type loggedSignature struct {
+	sig  []byte
+	cert *x509.Certificate
+	hash []byte
+}
+
+// signingTime returns when the signature was integrated into a transparency
+// log of the root, from the first of entries that verifies
+func (r *TrustedRoot) signingTime(entries []tlogEntry, signed loggedSignature) (time.Time, error) {
+	if len(entries) == 0 {
+		return time.Time{}, invalidf("no transparency log entry")
+	}
+	var err error
+	for _, e := range entries {
+		var t time.Time
+		if t, err = r.verifyEntry(e, signed); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// verifyEntry checks that e records signed and was logged by a log of the
+// root. It needs a signed entry timestamp (SET) or an inclusion proof with a
+// signed checkpoint, and checks every one present.
+func (r *TrustedRoot) verifyEntry(e tlogEntry, signed loggedSignature) (time.Time, error) {
+	// Rekor v2 entries carry no integration time and rely on a timestamp
+	// authority instead
+	if e.IntegratedTime == 0 {
+		return time.Time{}, invalidf("transparency log entry has no integration time")
+	}
+	integrated := time.Unix(e.IntegratedTime, 0)
+	key, err := lookupLog(r.tlogs, e.LogID.KeyID, integrated)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if e.InclusionPromise == nil && e.InclusionProof == nil {
+		return time.Time{}, invalidf("transparency log entry has neither a signed entry timestamp nor an inclusion proof")
+	}
+
+	if e.InclusionPromise != nil {
+		payload, err := json.Marshal(struct {
+			Body           string `json:"body"`
+			IntegratedTime int64  `json:"integratedTime"`
+			LogID          string `json:"logID"`
+			LogIndex       int64  `json:"logIndex"`
+		}{base64.StdEncoding.EncodeToString(e.CanonicalizedBody), e.IntegratedTime, hex.EncodeToString(e.LogID.KeyID), e.LogIndex})
+		if err != nil {
+			return time.Time{}, err
+		}
+		if verifyMessage(key, payload, e.InclusionPromise.SignedEntryTimestamp) != nil {
+			return time.Time{}, invalidf("signed entry timestamp mismatch")
+		}
+	}
+
+	if p := e.InclusionProof; p != nil {
+		leaf := sha256.Sum256(append([]byte{0}, e.CanonicalizedBody...))
+		root, err := rootFromInclusionProof(p.LogIndex, p.TreeSize, leaf[:], p.Hashes)
+		if err != nil {
+			return time.Time{}, invalidf("inclusion proof: %v", err)
+		}
+		if !bytes.Equal(root, p.RootHash) {
+			return time.Time{}, invalidf("inclusion proof does not lead to the tree root")
+		}
+		size, checkpointRoot, err := verifyCheckpoint(p.Checkpoint.Envelope, key)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if size != p.TreeSize || !bytes.Equal(checkpointRoot, p.RootHash) {
+			return time.Time{}, invalidf("checkpoint is for a different tree than the inclusion proof")
+		}
+	}
+
+	if err := checkLoggedBody(e.CanonicalizedBody, signed); err != nil {
+		return time.Time{}, err
+	}
+	return integrated, nil
+}
+
+// rekorBody is the subset of the hashedrekord v0.0.1, dsse v0.0.1 and intoto
+// v0.0.2 Rekor entry kinds that identifies a signature
+type rekorBody struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	Spec       struct {
+		// hashedrekord
+		Data struct {
+			Hash rekorHash `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   []byte `json:"content"`
+			PublicKey struct {
+				Content []byte `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+		// dsse
+		PayloadHash rekorHash `json:"payloadHash"`
+		Signatures  []struct {
+			Signature []byte `json:"signature"`
+			Verifier  []byte `json:"verifier"`
+		} `json:"signatures"`
+		// intoto
+		Content struct {
+			PayloadHash rekorHash `json:"payloadHash"`
+			Envelope    struct {
+				Signatures []struct {
+					Sig       []byte `json:"sig"` // Base64 of the base64-encoded signature
+					PublicKey []byte `json:"publicKey"`
+				} `json:"signatures"`
+			} `json:"envelope"`
+		} `json:"content"`
+	} `json:"spec"`
+}
+
+type rekorHash struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"value"`
+}
+
+// checkLoggedBody checks that a canonicalized Rekor entry records signed, so
+// that a valid entry for another signature can't vouch for this one
+func checkLoggedBody(body []byte, signed loggedSignature) error {
+	var b rekorBody
+	if err := json.Unmarshal(body, &b); err != nil {
+		return invalidf("malformed transparency log entry: %v", err)
+	}
+
+	type logged struct{ sig, key []byte }
+	var hash rekorHash
+	var sigs []logged
+	switch kind := b.Kind + " " + b.APIVersion; kind {
+	case "hashedrekord 0.0.1":
+		hash = b.Spec.Data.Hash
+		sigs = append(sigs, logged{b.Spec.Signature.Content, b.Spec.Signature.PublicKey.Content})
+	case "dsse 0.0.1":
+		hash = b.Spec.PayloadHash
+		for _, s := range b.Spec.Signatures {
+			sigs = append(sigs, logged{s.Signature, s.Verifier})
+		}
+	case "intoto 0.0.2":
+		hash = b.Spec.Content.PayloadHash
+		for _, s := range b.Spec.Content.Envelope.Signatures {
+			sig, err := base64.StdEncoding.DecodeString(string(s.Sig))
+			if err != nil {
+				return invalidf("malformed transparency log entry: %v", err)
+			}
+			sigs = append(sigs, logged{sig, s.PublicKey})
+		}
+	default:
+		return invalidf("unsupported transparency log entry kind %s", kind)
+	}
+
+	if hash.Algorithm != "sha256" || !strings.EqualFold(hash.Value, hex.EncodeToString(signed.hash)) {
+		return invalidf("transparency log entry is for a different artifact")
+	}
+	for _, s := range sigs {
+		block, _ := pem.Decode(s.key)
+		if block != nil && bytes.Equal(block.Bytes, signed.cert.Raw) && bytes.Equal(s.sig, signed.sig) {
+			return nil
+		}
+	}
+	return invalidf("transparency log entry is for a different signature")
+}
+
+// rootFromInclusionProof computes the RFC 6962 Merkle tree root of a tree of
+// size leaves from the hash of the leaf at index and its inclusion proof
+func rootFromInclusionProof(index, size int64, leaf []byte, proof [][]byte) ([]byte, error) {
+	if index < 0 || index >= size {
+		return nil, fmt.Errorf("leaf %d is outside a tree of size %d", index, size)
+	}
+	// The proof climbs to the node where the paths to index and the last leaf
+	// split, then along the right border of the tree
+	inner := bits.Len64(uint64(index ^ (size - 1)))
+	border := bits.OnesCount64(uint64(index) >> inner)
+	if len(proof) != inner+border {
+		return nil, fmt.Errorf("%d hashes, want %d", len(proof), inner+border)
+	}
+
+	hash := leaf
+	for i, sibling := range proof {
+		if i < inner && (index>>i)&1 == 0 {
+			hash = hashChildren(hash, sibling)
+		} else {
+			hash = hashChildren(sibling, hash)
+		}
+	}
+	return hash, nil
+}
+
+func hashChildren(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// verifyCheckpoint checks a checkpoint, a signed note committing to a tree
+// size and root hash, against the log's key and returns what it commits to
+func verifyCheckpoint(envelope string, key crypto.PublicKey) (int64, []byte, error) {
+	text, signatures, ok := strings.Cut(envelope, "\n\n")
+	if !ok {
+		return 0, nil, invalidf("malformed checkpoint")
+	}
+	text += "\n"
+
+	verified := false
+	for _, line := range strings.Split(signatures, "\n") {
+		// "— <name> <base64 of a 4-byte key hint and the signature>"
+		fields := strings.Fields(strings.TrimPrefix(line, "— "))
+		if len(fields) != 2 {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(fields[1])
+		if err == nil && len(sig) > 4 && verifyMessage(key, []byte(text), sig[4:]) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return 0, nil, invalidf("checkpoint signature mismatch")
+	}
+
+	// Origin, tree size, root hash, then optional extension lines
+	lines := strings.Split(text, "\n")
+	if len(lines) < 4 {
+		return 0, nil, invalidf("malformed checkpoint")
+	}
+	size, err := strconv.ParseInt(lines[1], 10, 64)
+	if err != nil {
+		return 0, nil, invalidf("malformed checkpoint tree size: %v", err)
+	}
+	root, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil {
+		return 0, nil, invalidf("malformed checkpoint root hash: %v", err)
+	}
+	return size, root, nil
+}
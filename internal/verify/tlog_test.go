@@ -0,0 +1,147 @@
+package verify
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// publicGoodBundle is an npm provenance attestation that sigstore-js's release
+// workflow logged to the public-good Rekor, with both a signed entry
+// timestamp and an inclusion proof
+const (
+	publicGoodBundle   = "testdata/sigstore-js-2.0.0-provenance.sigstore.json"
+	publicGoodIdentity = "https://github.com/sigstore/sigstore-js/.github/workflows/release.yml@refs/heads/main"
+)
+
+func TestVerifyCertificatePublicGood(t *testing.T) {
+	root, err := PublicGoodRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := Policy{Root: root, Identity: publicGoodIdentity, Issuer: testIssuer}
+
+	tests := []struct {
+		name    string
+		tamper  func(b *sigstoreBundle)
+		policy  Policy
+		wantErr bool
+	}{
+		{name: "as logged", policy: policy},
+		{name: "signed entry timestamp only", tamper: func(b *sigstoreBundle) {
+			b.VerificationMaterial.TlogEntries[0].InclusionProof = nil
+		}, policy: policy},
+		{name: "inclusion proof only", tamper: func(b *sigstoreBundle) {
+			b.VerificationMaterial.TlogEntries[0].InclusionPromise = nil
+		}, policy: policy},
+		{name: "other identity", policy: Policy{Root: root, Identity: "https://github.com/evil/repo/.github/workflows/release.yml@refs/heads/main"}, wantErr: true},
+		{name: "tampered signed entry timestamp", tamper: func(b *sigstoreBundle) {
+			b.VerificationMaterial.TlogEntries[0].InclusionProof = nil
+			b.VerificationMaterial.TlogEntries[0].InclusionPromise.SignedEntryTimestamp[10] ^= 1
+		}, policy: policy, wantErr: true},
+		{name: "tampered inclusion proof", tamper: func(b *sigstoreBundle) {
+			b.VerificationMaterial.TlogEntries[0].InclusionPromise = nil
+			b.VerificationMaterial.TlogEntries[0].InclusionProof.Hashes[0][0] ^= 1
+		}, policy: policy, wantErr: true},
+		{name: "tampered checkpoint", tamper: func(b *sigstoreBundle) {
+			b.VerificationMaterial.TlogEntries[0].InclusionPromise = nil
+			p := b.VerificationMaterial.TlogEntries[0].InclusionProof
+			p.Checkpoint.Envelope = string(bytes.Replace([]byte(p.Checkpoint.Envelope), []byte(fmt.Sprint(p.TreeSize)), []byte(fmt.Sprint(p.TreeSize+1)), 1))
+		}, policy: policy, wantErr: true},
+		{name: "integrated later", tamper: func(b *sigstoreBundle) {
+			b.VerificationMaterial.TlogEntries[0].InclusionPromise = nil
+			b.VerificationMaterial.TlogEntries[0].IntegratedTime += 3600
+		}, policy: policy, wantErr: true},
+		{name: "no log entry", tamper: func(b *sigstoreBundle) {
+			b.VerificationMaterial.TlogEntries = nil
+		}, policy: policy, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(publicGoodBundle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b sigstoreBundle
+			if err := json.Unmarshal(data, &b); err != nil {
+				t.Fatal(err)
+			}
+			if tt.tamper != nil {
+				tt.tamper(&b)
+			}
+			cert, err := x509.ParseCertificate(b.VerificationMaterial.X509CertificateChain.Certificates[0].RawBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			payloadHash := sha256.Sum256(b.DSSEEnvelope.Payload)
+			signed := loggedSignature{sig: b.DSSEEnvelope.Signatures[0].Sig, cert: cert, hash: payloadHash[:]}
+
+			err = tt.policy.verifyCertificate(b.VerificationMaterial.TlogEntries, signed)
+			if !tt.wantErr && err != nil {
+				t.Fatalf("verifyCertificate() error = %v", err)
+			}
+			if tt.wantErr && !errors.Is(err, ErrSignatureInvalid) {
+				t.Errorf("verifyCertificate() error = %v, want %v", err, ErrSignatureInvalid)
+			}
+		})
+	}
+}
+
+// merkleRoot is the RFC 6962 Merkle tree hash of leaves
+func merkleRoot(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := 1
+	for k*2 < len(leaves) {
+		k *= 2
+	}
+	return hashChildren(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+// merklePath is the RFC 6962 inclusion proof of the leaf at index
+func merklePath(index int, leaves [][]byte) [][]byte {
+	if len(leaves) == 1 {
+		return nil
+	}
+	k := 1
+	for k*2 < len(leaves) {
+		k *= 2
+	}
+	if index < k {
+		return append(merklePath(index, leaves[:k]), merkleRoot(leaves[k:]))
+	}
+	return append(merklePath(index-k, leaves[k:]), merkleRoot(leaves[:k]))
+}
+
+func TestRootFromInclusionProof(t *testing.T) {
+	for size := 1; size <= 17; size++ {
+		leaves := make([][]byte, size)
+		for i := range leaves {
+			h := sha256.Sum256([]byte{0, byte(i)})
+			leaves[i] = h[:]
+		}
+		want := merkleRoot(leaves)
+		for index := range size {
+			proof := merklePath(index, leaves)
+			got, err := rootFromInclusionProof(int64(index), int64(size), leaves[index], proof)
+			if err != nil || !bytes.Equal(got, want) {
+				t.Errorf("rootFromInclusionProof(%d, %d) = %x, %v, want %x", index, size, got, err, want)
+			}
+			if len(proof) > 0 {
+				if _, err := rootFromInclusionProof(int64(index), int64(size), leaves[index], proof[1:]); err == nil {
+					t.Errorf("rootFromInclusionProof(%d, %d) accepted a truncated proof", index, size)
+				}
+			}
+		}
+	}
+	if _, err := rootFromInclusionProof(4, 4, make([]byte, sha256.Size), nil); err == nil {
+		t.Error("rootFromInclusionProof() accepted an index outside the tree")
+	}
+}
@@ -0,0 +1,187 @@
+package verify
+
+import (
+	"crypto"
+	"crypto/x509"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// publicGoodRoot is a snapshot of the trusted_root.json that Sigstore's
+// public-good instance distributes over TUF
+//
+//go:embed trusted_root.json
+var publicGoodRoot []byte
+
+// TrustedRoot holds what keyless signatures are checked against: the
+// certificate authorities that issue signing certificates, and the keys of
+// the transparency logs (Rekor) and certificate transparency logs that
+// record them
+type TrustedRoot struct {
+	authorities []certAuthority
+	tlogs       map[string]logKey // By log ID, the SHA-256 of the key
+	ctlogs      map[string]logKey
+}
+
+// validity is the period a CA or log key is trusted for; a zero end is open
+type validity struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+func (v validity) contains(t time.Time) bool {
+	return !t.Before(v.Start) && (v.End.IsZero() || !t.After(v.End))
+}
+
+type certAuthority struct {
+	roots         *x509.CertPool
+	intermediates *x509.CertPool
+	validFor      validity
+}
+
+type logKey struct {
+	key      crypto.PublicKey
+	validFor validity
+}
+
+// trustedRootFile is the subset of the Sigstore trusted root format needed
+// for verification. Timestamp authorities are ignored.
+type trustedRootFile struct {
+	MediaType   string    `json:"mediaType"`
+	Tlogs       []logFile `json:"tlogs"`
+	Authorities []struct {
+		CertChain struct {
+			Certificates []struct {
+				RawBytes []byte `json:"rawBytes"`
+			} `json:"certificates"`
+		} `json:"certChain"`
+		ValidFor validity `json:"validFor"`
+	} `json:"certificateAuthorities"`
+	Ctlogs []logFile `json:"ctlogs"`
+}
+
+type logFile struct {
+	PublicKey struct {
+		RawBytes []byte   `json:"rawBytes"`
+		ValidFor validity `json:"validFor"`
+	} `json:"publicKey"`
+	LogID struct {
+		KeyID []byte `json:"keyId"`
+	} `json:"logId"`
+}
+
+// PublicGoodRoot returns the trusted root of Sigstore's public-good instance,
+// which cosign uses by default and GitHub uses for attestations of public
+// repositories
+func PublicGoodRoot() (*TrustedRoot, error) {
+	return ParseTrustedRoot(publicGoodRoot)
+}
+
+// LoadTrustedRoot reads a Sigstore trusted_root.json
+func LoadTrustedRoot(path string) (*TrustedRoot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	root, err := ParseTrustedRoot(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return root, nil
+}
+
+// ParseTrustedRoot parses a Sigstore trusted_root.json. Each certificate
+// chain lists the issuing CA first and its root last.
+func ParseTrustedRoot(data []byte) (*TrustedRoot, error) {
+	var f trustedRootFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("malformed trusted root: %w", err)
+	}
+
+	root := &TrustedRoot{}
+	for _, ca := range f.Authorities {
+		certs := ca.CertChain.Certificates
+		if len(certs) == 0 {
+			return nil, errors.New("certificate authority without certificates")
+		}
+		authority := certAuthority{roots: x509.NewCertPool(), intermediates: x509.NewCertPool(), validFor: ca.ValidFor}
+		for i, c := range certs {
+			cert, err := x509.ParseCertificate(c.RawBytes)
+			if err != nil {
+				return nil, fmt.Errorf("certificate authority: %w", err)
+			}
+			if i == len(certs)-1 {
+				authority.roots.AddCert(cert)
+			} else {
+				authority.intermediates.AddCert(cert)
+			}
+		}
+		root.authorities = append(root.authorities, authority)
+	}
+	if len(root.authorities) == 0 {
+		return nil, errors.New("no certificate authorities in trusted root")
+	}
+
+	var err error
+	if root.tlogs, err = parseLogs(f.Tlogs); err != nil {
+		return nil, fmt.Errorf("transparency log: %w", err)
+	}
+	if root.ctlogs, err = parseLogs(f.Ctlogs); err != nil {
+		return nil, fmt.Errorf("certificate transparency log: %w", err)
+	}
+	return root, nil
+}
+
+func parseLogs(logs []logFile) (map[string]logKey, error) {
+	keys := make(map[string]logKey, len(logs))
+	for _, l := range logs {
+		key, err := x509.ParsePKIXPublicKey(l.PublicKey.RawBytes)
+		if err != nil {
+			return nil, err
+		}
+		keys[string(l.LogID.KeyID)] = logKey{key: key, validFor: l.PublicKey.ValidFor}
+	}
+	return keys, nil
+}
+
+// lookupLog returns the key of the log with id, if it was trusted at t
+func lookupLog(logs map[string]logKey, id []byte, t time.Time) (crypto.PublicKey, error) {
+	l, ok := logs[string(id)]
+	if !ok {
+		return nil, invalidf("log %x is not in the trusted root", id)
+	}
+	if !l.validFor.contains(t) {
+		return nil, invalidf("log %x was not trusted at %s", id, t.UTC().Format(time.RFC3339))
+	}
+	return l.key, nil
+}
+
+// verifyChain checks cert chains to a CA that was valid at t and returns the
+// certificate that issued it
+func (r *TrustedRoot) verifyChain(cert *x509.Certificate, t time.Time) (*x509.Certificate, error) {
+	err := invalidf("no certificate authority was valid at %s", t.UTC().Format(time.RFC3339))
+	for _, ca := range r.authorities {
+		if !ca.validFor.contains(t) {
+			continue
+		}
+		var chains [][]*x509.Certificate
+		chains, err = cert.Verify(x509.VerifyOptions{
+			Roots:         ca.roots,
+			Intermediates: ca.intermediates,
+			CurrentTime:   t,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		})
+		if err == nil && len(chains[0]) > 1 {
+			return chains[0][1], nil
+		}
+		if err == nil {
+			return nil, invalidf("signing certificate is itself a trusted root")
+		}
+		err = invalidf("certificate: %v", err)
+	}
+	return nil, err
+}
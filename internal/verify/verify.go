@@ -0,0 +1,158 @@
+// Package verify checks release asset signatures offline.
+package verify
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var (
+	// ErrSignatureMissing means no signature was published for the asset
+	ErrSignatureMissing = errors.New("no signature published")
+	// ErrSignatureInvalid means a signature was found but does not verify
+	ErrSignatureInvalid = errors.New("signature verification failed")
+)
+
+// invalidf returns an error wrapping ErrSignatureInvalid
+func invalidf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrSignatureInvalid, fmt.Sprintf(format, args...))
+}
+
+// Policy describes what a signature must chain to. Either PublicKey is set,
+// or Root plus an identity (exact or regexp) and, optionally, an OIDC issuer
+// for keyless (Fulcio certificate) signatures.
+type Policy struct {
+	PublicKey      crypto.PublicKey
+	Root           *TrustedRoot
+	Identity       string
+	IdentityRegexp *regexp.Regexp
+	Issuer         string
+}
+
+// ParsePublicKey parses a PEM-encoded PKIX public key
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found in public key")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// verifyCertificate checks a keyless signing certificate against the policy.
+// A transparency log entry must record the signature, and the certificate
+// must have been valid, chained to the root and been published to a
+// certificate transparency log at the time the entry was integrated.
+func (p Policy) verifyCertificate(entries []tlogEntry, signed loggedSignature) error {
+	if p.Root == nil {
+		return errors.New("keyless signature requires a trusted root")
+	}
+	cert := signed.cert
+
+	signedAt, err := p.Root.signingTime(entries, signed)
+	if err != nil {
+		return err
+	}
+	if signedAt.Before(cert.NotBefore) || signedAt.After(cert.NotAfter) {
+		return invalidf("signed at %s, outside the certificate's validity from %s to %s",
+			signedAt.UTC().Format(time.RFC3339), cert.NotBefore.UTC().Format(time.RFC3339), cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+	issuer, err := p.Root.verifyChain(cert, signedAt)
+	if err != nil {
+		return err
+	}
+	if err := p.Root.verifySCT(cert, issuer); err != nil {
+		return err
+	}
+
+	if !p.matchesIdentity(certIdentities(cert)) {
+		return invalidf("certificate identity %v does not match policy", certIdentities(cert))
+	}
+	if p.Issuer != "" {
+		if issuer := certIssuer(cert); issuer != p.Issuer {
+			return invalidf("certificate issuer %q does not match %q", issuer, p.Issuer)
+		}
+	}
+	return nil
+}
+
+func (p Policy) matchesIdentity(identities []string) bool {
+	if p.Identity == "" && p.IdentityRegexp == nil {
+		return false
+	}
+	for _, id := range identities {
+		if p.Identity != "" && id == p.Identity {
+			return true
+		}
+		if p.IdentityRegexp != nil && p.IdentityRegexp.MatchString(id) {
+			return true
+		}
+	}
+	return false
+}
+
+func certIdentities(cert *x509.Certificate) []string {
+	var ids []string
+	for _, u := range cert.URIs {
+		ids = append(ids, u.String())
+	}
+	ids = append(ids, cert.EmailAddresses...)
+	return ids
+}
+
+var (
+	oidIssuerV1 = []int{1, 3, 6, 1, 4, 1, 57264, 1, 1} // Raw string
+	oidIssuerV2 = []int{1, 3, 6, 1, 4, 1, 57264, 1, 8} // DER UTF8String
+)
+
+// certIssuer returns the OIDC issuer recorded by Fulcio
+func certIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidIssuerV2) && len(ext.Value) > 2 {
+			return string(ext.Value[2:])
+		}
+	}
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidIssuerV1) {
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+// verifyDigest checks sig over a message whose SHA-256 is digest
+func verifyDigest(pub crypto.PublicKey, digest, sig []byte) error {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest, sig) {
+			return invalidf("ecdsa signature mismatch")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, sig); err != nil {
+			return invalidf("rsa: %v", err)
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", pub)
+	}
+	return nil
+}
+
+// verifyMessage checks sig over msg
+func verifyMessage(pub crypto.PublicKey, msg, sig []byte) error {
+	if key, ok := pub.(ed25519.PublicKey); ok {
+		if !ed25519.Verify(key, msg, sig) {
+			return invalidf("ed25519 signature mismatch")
+		}
+		return nil
+	}
+	digest := sha256.Sum256(msg)
+	return verifyDigest(pub, digest[:], sig)
+}
@@ -57,4 +57,30 @@ function M.get_binary()
   return bin_path
 end
 
+-- supports_output reports whether bin accepts --output, which sous-chef
+-- 0.1.0 added. A development build ("dev") is assumed current; a development
+-- binary copied from an older release still needs the plain output.
+function M.supports_output(bin)
+  local ok, stdout = pcall(cmd.exec, bin .. " version")
+  if not ok then
+    return false
+  end
+  local version = stdout:match("^%s*v?(%S+)")
+  if version == "dev" then
+    return true
+  end
+  local major, minor = (version or ""):match("^(%d+)%.(%d+)")
+  if not major then
+    return false
+  end
+  major, minor = tonumber(major), tonumber(minor)
+  return major > 0 or minor >= 1
+end
+
+-- Completions and man pages of every installed tool are linked into this
+-- directory, so one MANPATH and FPATH entry covers them all
+function M.share_dir()
+  return file.join_path(RUNTIME.pluginDirPath, "share")
+end
+
 return M
@@ -1,14 +1,27 @@
 package main
 
 import (
+	"cmp"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
+	"time"
 
+	"github.com/aniaan/sous-chef/internal/cache"
+	"github.com/aniaan/sous-chef/internal/config"
 	"github.com/aniaan/sous-chef/internal/gh"
 	"github.com/aniaan/sous-chef/internal/installer"
+	"github.com/aniaan/sous-chef/internal/lock"
+	"github.com/aniaan/sous-chef/internal/output"
+	"github.com/aniaan/sous-chef/internal/progress"
 	"github.com/aniaan/sous-chef/internal/registry"
+	"github.com/aniaan/sous-chef/internal/source"
+	"github.com/aniaan/sous-chef/internal/util"
+	"github.com/aniaan/sous-chef/internal/verify"
 )
 
 var Version = "dev"
@@ -16,9 +29,10 @@ var Version = "dev"
 func main() {
 	if len(os.Args) < 2 {
 		printUsage()
-		os.Exit(1)
+		os.Exit(output.ExitUsage)
 	}
 
+	gh.UserAgent = "sous-chef/" + Version
 	cmd := os.Args[1]
 
 	switch cmd {
@@ -29,159 +43,547 @@ func main() {
 		listCmd := flag.NewFlagSet("list-versions", flag.ExitOnError)
 		tool := listCmd.String("tool", "", "Tool name")
 		withPublishedAt := listCmd.Bool("with-published-at", false, "Show published date")
+		limit := listCmd.Int("limit", 10, "Maximum number of versions to show (0 = all)")
+		maxPages := listCmd.Int("max-pages", 0, "Maximum number of release pages to fetch")
+		format := outputFlag(listCmd)
+		wait := waitFlag(listCmd)
+		offline := offlineFlag(listCmd)
 		listCmd.Parse(os.Args[2:])
 
+		w := newWriter(*format)
 		if *tool == "" {
-			fmt.Println("Error: --tool is required")
-			os.Exit(1)
+			fail(w, output.CodeUsage, "", errors.New("--tool is required"))
 		}
-		runListVersions(*tool, *withPublishedAt)
+		runListVersions(w, newClient(w, *maxPages, *wait, *offline), *tool, *withPublishedAt, *limit)
 
 	case "install":
 		installCmd := flag.NewFlagSet("install", flag.ExitOnError)
 		tool := installCmd.String("tool", "", "Tool name")
-		version := installCmd.String("version", "", "Version to install")
+		version := installCmd.String("version", "", "Version, range or alias to install")
 		dir := installCmd.String("dir", "", "Installation directory")
+		var flags installFlags
+		flags.register(installCmd)
 		installCmd.Parse(os.Args[2:])
 
+		w := newWriter(flags.output)
 		if *tool == "" || *version == "" || *dir == "" {
-			fmt.Println("Error: --tool, --version, and --dir are required")
-			os.Exit(1)
+			fail(w, output.CodeUsage, *tool, errors.New("--tool, --version, and --dir are required"))
 		}
-		runInstall(*tool, *version, *dir)
+		runInstall(w, *tool, *version, *dir, flags)
 
 	case "install-latest":
 		installCmd := flag.NewFlagSet("install-latest", flag.ExitOnError)
 		tool := installCmd.String("tool", "", "Tool name")
 		dir := installCmd.String("dir", "", "Installation directory")
+		var flags installFlags
+		flags.register(installCmd)
 		installCmd.Parse(os.Args[2:])
 
+		w := newWriter(flags.output)
 		if *tool == "" || *dir == "" {
-			fmt.Println("Error: --tool and --dir are required")
-			os.Exit(1)
+			fail(w, output.CodeUsage, *tool, errors.New("--tool and --dir are required"))
 		}
-		runInstallLatest(*tool, *dir)
+		runInstallLatest(w, *tool, *dir, flags)
+
+	case "resolve":
+		resolveCmd := flag.NewFlagSet("resolve", flag.ExitOnError)
+		tool := resolveCmd.String("tool", "", "Tool name")
+		version := resolveCmd.String("version", "", "Version, range (^0.10, ~1.2, >=0.40 <0.50, 1.x) or alias (latest, latest-stable, prerelease)")
+		maxPages := resolveCmd.Int("max-pages", 0, "Maximum number of release pages to fetch")
+		format := outputFlag(resolveCmd)
+		wait := waitFlag(resolveCmd)
+		offline := offlineFlag(resolveCmd)
+		resolveCmd.Parse(os.Args[2:])
+
+		w := newWriter(*format)
+		if *tool == "" || *version == "" {
+			fail(w, output.CodeUsage, *tool, errors.New("--tool and --version are required"))
+		}
+		runResolve(w, newClient(w, *maxPages, *wait, *offline), *tool, *version)
 
 	case "list-latest-versions":
-		runListLatestVersions()
+		latestCmd := flag.NewFlagSet("list-latest-versions", flag.ExitOnError)
+		format := outputFlag(latestCmd)
+		wait := waitFlag(latestCmd)
+		offline := offlineFlag(latestCmd)
+		latestCmd.Parse(os.Args[2:])
+
+		w := newWriter(*format)
+		runListLatestVersions(w, newClient(w, 0, *wait, *offline))
+
+	case "rate-limit":
+		rateLimitCmd := flag.NewFlagSet("rate-limit", flag.ExitOnError)
+		format := outputFlag(rateLimitCmd)
+		rateLimitCmd.Parse(os.Args[2:])
+
+		runRateLimit(newWriter(*format))
+
+	case "auth":
+		runAuth(output.NewWriter(output.Text))
+
+	case "cache":
+		if len(os.Args) < 3 {
+			printUsage()
+			os.Exit(output.ExitUsage)
+		}
+		runCache(output.NewWriter(output.Text), os.Args[2])
 
 	default:
 		printUsage()
-		os.Exit(1)
+		os.Exit(output.ExitUsage)
 	}
 }
 
+// printUsage writes usage to stderr; it is only shown for invalid invocations
 func printUsage() {
-	fmt.Println("Usage: sous-chef <command> [args]")
-	fmt.Println("Commands:")
-	fmt.Println("  version")
-	fmt.Println("  list-versions --tool <name> [--with-published-at]")
-	fmt.Println("  list-latest-versions")
-	fmt.Println("  install --tool <name> --version <ver> --dir <path>")
-	fmt.Println("  install-latest --tool <name> --dir <path>")
+	fmt.Fprintln(os.Stderr, "Usage: sous-chef <command> [args]")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  version")
+	fmt.Fprintln(os.Stderr, "  list-versions --tool <name> [--with-published-at] [--limit <n>] [--max-pages <n>] [--wait-on-rate-limit] [--offline] [--output <format>]")
+	fmt.Fprintln(os.Stderr, "  list-latest-versions [--wait-on-rate-limit] [--offline] [--output <format>]")
+	fmt.Fprintln(os.Stderr, "  resolve --tool <name> --version <spec> [--max-pages <n>] [--wait-on-rate-limit] [--offline] [--output <format>]")
+	fmt.Fprintln(os.Stderr, "  install --tool <name> --version <ver> --dir <path> [--require-checksum] [--require-signature] [--lock-timeout <dur>] [--progress <mode>] [--wait-on-rate-limit] [--offline] [--share-dir <path>] [--output <format>]")
+	fmt.Fprintln(os.Stderr, "  install-latest --tool <name> --dir <path> [--require-checksum] [--require-signature] [--lock-timeout <dur>] [--progress <mode>] [--wait-on-rate-limit] [--offline] [--share-dir <path>] [--output <format>]")
+	fmt.Fprintln(os.Stderr, "  rate-limit [--output <format>]")
+	fmt.Fprintln(os.Stderr, "  auth")
+	fmt.Fprintln(os.Stderr, "  cache info|clear")
 }
 
-func runInstallLatest(toolName, dir string) {
-	plugin, ok := registry.Registry[toolName]
-	if !ok {
-		fmt.Printf("Error: Tool '%s' not found in registry\n", toolName)
-		os.Exit(1)
+// envBool reports whether an environment variable is set to a true value
+func envBool(name string) bool {
+	v, _ := strconv.ParseBool(os.Getenv(name))
+	return v
+}
+
+// envDuration parses a duration from an environment variable, returning 0 if unset or invalid
+func envDuration(name string) time.Duration {
+	d, _ := time.ParseDuration(os.Getenv(name))
+	return d
+}
+
+// outputFlag registers --output, defaulting to SOUS_CHEF_OUTPUT
+func outputFlag(fs *flag.FlagSet) *string {
+	return fs.String("output", os.Getenv("SOUS_CHEF_OUTPUT"), "Output format: text, json or ndjson")
+}
+
+// waitFlag registers --wait-on-rate-limit, defaulting to SOUS_CHEF_WAIT_ON_RATE_LIMIT
+func waitFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("wait-on-rate-limit", envBool("SOUS_CHEF_WAIT_ON_RATE_LIMIT"), "Wait for the GitHub API rate limit to reset instead of failing")
+}
+
+// offlineFlag registers --offline, defaulting to SOUS_CHEF_OFFLINE
+func offlineFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("offline", envBool("SOUS_CHEF_OFFLINE"), "Use only the caches and the local mirror, never the network")
+}
+
+func newWriter(format string) *output.Writer {
+	f, err := output.ParseFormat(format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(output.ExitUsage)
 	}
+	return output.NewWriter(f)
+}
+
+// fail reports err on stderr, as a JSON error object in structured output,
+// and exits with the exit code for code
+func fail(w *output.Writer, code, tool string, err error) {
+	w.Error(code, tool, err)
+	os.Exit(output.ExitCode(code))
+}
+
+// errorCode maps well-known errors to their stable code, or returns fallback
+func errorCode(err error, fallback string) string {
+	var rateLimit *gh.RateLimitError
+	var platform *util.UnsupportedPlatformError
+	switch {
+	case errors.As(err, &rateLimit):
+		return output.CodeRateLimited
+	case errors.Is(err, gh.ErrOffline):
+		return output.CodeOffline
+	case errors.As(err, &platform):
+		return output.CodeUnsupportedPlatform
+	case errors.Is(err, installer.ErrBinaryNotFound):
+		return output.CodeBinaryNotFound
+	case errors.Is(err, registry.ErrNoMatch):
+		return output.CodeNoMatchingRelease
+	case errors.Is(err, gh.ErrNotFound):
+		return output.CodeNotFound
+	case errors.Is(err, lock.ErrTimeout):
+		return output.CodeLockTimeout
+	case errors.Is(err, installer.ErrChecksumMismatch), errors.Is(err, installer.ErrChecksumMissing):
+		return output.CodeChecksum
+	case errors.Is(err, verify.ErrSignatureInvalid), errors.Is(err, verify.ErrSignatureMissing):
+		return output.CodeSignature
+	}
+	return fallback
+}
+
+// versionRecord is the structured form of a release
+type versionRecord struct {
+	Tool        string    `json:"tool"`
+	Version     string    `json:"version"`
+	Tag         string    `json:"tag"`
+	PublishedAt time.Time `json:"published_at,omitzero"`
+	Prerelease  bool      `json:"prerelease"`
+}
+
+func newVersionRecord(plugin *registry.PluginConfig, r source.Release) versionRecord {
+	return versionRecord{
+		Tool:        plugin.Name,
+		Version:     plugin.GetDisplayVersion(r.TagName),
+		Tag:         r.TagName,
+		PublishedAt: r.PublishedAt,
+		Prerelease:  r.Prerelease,
+	}
+}
 
+// installRecord is the structured result of an install
+type installRecord struct {
+	versionRecord
+	Asset          string                   `json:"asset"`
+	DownloadURL    string                   `json:"download_url"`
+	Checksum       string                   `json:"checksum,omitempty"`
+	ChecksumStatus installer.ChecksumStatus `json:"checksum_status"`
+	InstallPath    string                   `json:"install_path"`
+}
+
+// newClient creates a GitHub client backed by the metadata cache. A maxPages of 0 falls back to
+// SOUS_CHEF_MAX_PAGES, then to gh.DefaultMaxPages. SOUS_CHEF_GITHUB_API_URL and
+// SOUS_CHEF_GITHUB_URL point it at another GitHub instance. Mirrors, the CA
+// bundle and the local mirror come from SOUS_CHEF_MIRRORS, SOUS_CHEF_CA_BUNDLE
+// and SOUS_CHEF_LOCAL_MIRROR, then config.toml.
+func newClient(w *output.Writer, maxPages int, waitOnRateLimit, offline bool) *gh.Client {
 	client := gh.NewClient()
-	releases, err := plugin.GetReleases(client)
+	metadata, err := cache.OpenMetadata()
 	if err != nil {
-		fmt.Printf("Error fetching releases: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Warning: metadata cache disabled: %v\n", err)
+	} else {
+		client.Cache = metadata
 	}
-
-	if len(releases) == 0 {
-		fmt.Printf("Error: No releases found for %s\n", toolName)
-		os.Exit(1)
+	if maxPages == 0 {
+		maxPages, _ = strconv.Atoi(os.Getenv("SOUS_CHEF_MAX_PAGES"))
 	}
+	client.MaxPages = maxPages
+	client.WaitOnRateLimit = waitOnRateLimit
+	client.Offline = offline
+	client.APIBaseURL = os.Getenv("SOUS_CHEF_GITHUB_API_URL")
+	client.WebBaseURL = os.Getenv("SOUS_CHEF_GITHUB_URL")
 
-	latest := releases[0]
-	displayVersion := plugin.GetDisplayVersion(latest.TagName)
+	cfg, err := config.Load()
+	if err != nil {
+		fail(w, output.CodeConfig, "", err)
+	}
+	if client.Mirrors, err = gh.ParseMirrors(os.Getenv("SOUS_CHEF_MIRRORS")); err != nil {
+		fail(w, output.CodeConfig, "", fmt.Errorf("SOUS_CHEF_MIRRORS: %w", err))
+	}
+	for _, m := range cfg.Mirrors {
+		mirror, err := gh.ParseMirror(m.From, m.To)
+		if err != nil {
+			fail(w, output.CodeConfig, "", err)
+		}
+		client.Mirrors = append(client.Mirrors, mirror)
+	}
+	caBundle := cmp.Or(os.Getenv("SOUS_CHEF_CA_BUNDLE"), cfg.CABundle)
+	if caBundle != "" {
+		if err := client.LoadCABundle(caBundle); err != nil {
+			fail(w, output.CodeConfig, "", err)
+		}
+	}
+	client.LocalMirror = cmp.Or(os.Getenv("SOUS_CHEF_LOCAL_MIRROR"), cfg.LocalMirror)
+	return client
+}
 
-	fmt.Printf("Found latest version: %s (tag: %s)\n", displayVersion, latest.TagName)
-	runInstall(toolName, displayVersion, dir)
+func loadRegistry(w *output.Writer) map[string]*registry.PluginConfig {
+	reg, err := registry.Load()
+	if err != nil {
+		fail(w, output.CodeRegistry, "", fmt.Errorf("failed to load registry: %w", err))
+	}
+	return reg
 }
 
-func runListVersions(toolName string, withPublishedAt bool) {
-	plugin, ok := registry.Registry[toolName]
+func lookupTool(w *output.Writer, toolName string) *registry.PluginConfig {
+	plugin, ok := loadRegistry(w)[toolName]
 	if !ok {
-		fmt.Printf("Error: Tool '%s' not found in registry\n", toolName)
-		os.Exit(1)
+		fail(w, output.CodeUnknownTool, toolName, fmt.Errorf("tool '%s' not found in registry", toolName))
 	}
+	return plugin
+}
 
-	client := gh.NewClient()
-	releases, err := plugin.GetReleases(client)
+// installFlags holds the flags shared by install and install-latest
+type installFlags struct {
+	requireChecksum  bool
+	requireSignature bool
+	lockTimeout      time.Duration
+	progress         string
+	waitOnRateLimit  bool
+	offline          bool
+	shareDir         string
+	output           string
+}
+
+func (f *installFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.requireChecksum, "require-checksum", envBool("SOUS_CHEF_REQUIRE_CHECKSUM"), "Refuse to install unverified assets")
+	fs.BoolVar(&f.requireSignature, "require-signature", envBool("SOUS_CHEF_REQUIRE_SIGNATURE"), "Refuse to install when a declared signature is not published")
+	fs.DurationVar(&f.lockTimeout, "lock-timeout", envDuration("SOUS_CHEF_LOCK_TIMEOUT"), "How long to wait for another install holding the same directory")
+	fs.StringVar(&f.progress, "progress", os.Getenv("SOUS_CHEF_PROGRESS"), "Progress output: auto, bar, plain, json or none")
+	fs.BoolVar(&f.waitOnRateLimit, "wait-on-rate-limit", envBool("SOUS_CHEF_WAIT_ON_RATE_LIMIT"), "Wait for the GitHub API rate limit to reset instead of failing")
+	fs.BoolVar(&f.offline, "offline", envBool("SOUS_CHEF_OFFLINE"), "Use only the caches and the local mirror, never the network")
+	fs.StringVar(&f.shareDir, "share-dir", os.Getenv("SOUS_CHEF_SHARE_DIR"), "Also link completions and man pages into this directory, shared by every tool")
+	fs.StringVar(&f.output, "output", os.Getenv("SOUS_CHEF_OUTPUT"), "Output format: text, json or ndjson")
+}
+
+// reporter creates the progress reporter. JSON progress events go to stdout,
+// unless it is reserved for structured output.
+func (f *installFlags) reporter(w *output.Writer) progress.Reporter {
+	mode, err := progress.ParseMode(f.progress)
 	if err != nil {
-		fmt.Printf("Error fetching releases: %v\n", err)
-		os.Exit(1)
+		fail(w, output.CodeUsage, "", err)
+	}
+	if w.Format().Structured() {
+		return progress.New(mode, os.Stderr)
 	}
+	return progress.New(mode, os.Stdout)
+}
 
-	// Limit to top 10
-	limit := min(len(releases), 10)
+func runInstallLatest(w *output.Writer, toolName, dir string, flags installFlags) {
+	runInstall(w, toolName, registry.AliasLatest, dir, flags)
+}
+
+func runResolve(w *output.Writer, client *gh.Client, toolName, spec string) {
+	plugin := lookupTool(w, toolName)
+
+	release, err := plugin.Resolve(source.Open(client, plugin.Source), spec)
+	if err != nil {
+		fail(w, errorCode(err, output.CodeFetchFailed), toolName, fmt.Errorf("failed to resolve %s@%s: %w", toolName, spec, err))
+	}
+	if w.Format().Structured() {
+		w.Object(newVersionRecord(plugin, release))
+		return
+	}
+	fmt.Println(plugin.GetDisplayVersion(release.TagName))
+}
 
-	topReleases := releases[:limit]
+func runListVersions(w *output.Writer, client *gh.Client, toolName string, withPublishedAt bool, limit int) {
+	plugin := lookupTool(w, toolName)
+
+	releases, err := plugin.GetReleases(source.Open(client, plugin.Source), limit)
+	if err != nil {
+		fail(w, errorCode(err, output.CodeFetchFailed), toolName, fmt.Errorf("failed to fetch releases: %w", err))
+	}
+
+	topReleases := releases
+	if limit > 0 {
+		topReleases = releases[:min(len(releases), limit)]
+	}
 
 	// Print in reverse (oldest first, so newest is at the bottom of the terminal)
 	for i := len(topReleases) - 1; i >= 0; i-- {
 		r := topReleases[i]
 		v := plugin.GetDisplayVersion(r.TagName)
 
-		if withPublishedAt {
+		switch {
+		case w.Format().Structured():
+			w.Record(newVersionRecord(plugin, r))
+		case withPublishedAt:
 			// Format: version #2023-10-27T10:00:00Z
 			fmt.Printf("%s #%s\n", v, r.PublishedAt.Format("2006-01-02T15:04:05Z"))
-		} else {
+		default:
 			fmt.Println(v)
 		}
 	}
+	w.Flush()
 }
 
-func runListLatestVersions() {
+func runListLatestVersions(w *output.Writer, client *gh.Client) {
 	// Sort plugin names for consistent output
+	reg := loadRegistry(w)
 	var plugins []string
-	for name := range registry.Registry {
+	for name := range reg {
 		plugins = append(plugins, name)
 	}
 	sort.Strings(plugins)
 
-	client := gh.NewClient()
-
 	for _, name := range plugins {
-		plugin := registry.Registry[name]
-		releases, err := plugin.GetReleases(client)
+		plugin := reg[name]
+		releases, err := plugin.GetReleases(source.Open(client, plugin.Source), 1)
+		if err == nil && len(releases) == 0 {
+			err = fmt.Errorf("%w: no matching releases found", registry.ErrNoMatch)
+		}
 		if err != nil {
-			fmt.Printf("%s: Error fetching releases: %v\n", name, err)
+			switch {
+			case w.Format().Structured():
+				w.Record(output.ErrorObject{Error: output.ErrorDetail{
+					Code:    errorCode(err, output.CodeFetchFailed),
+					Message: err.Error(),
+					Tool:    name,
+				}})
+			case errors.Is(err, registry.ErrNoMatch):
+				fmt.Fprintf(os.Stderr, "%s: No matching releases found\n", name)
+			default:
+				fmt.Fprintf(os.Stderr, "%s: Error fetching releases: %v\n", name, err)
+			}
 			continue
 		}
 
-		if len(releases) == 0 {
-			fmt.Printf("%s: No matching releases found\n", name)
+		latest := releases[0]
+		if w.Format().Structured() {
+			w.Record(newVersionRecord(plugin, latest))
 			continue
 		}
-
-		latest := releases[0]
 		v := plugin.GetDisplayVersion(latest.TagName)
 
 		fmt.Printf("%s: %s #%s\n", name, v, latest.PublishedAt.Format("2006-01-02T15:04:05Z"))
 	}
+	w.Flush()
 }
 
-func runInstall(toolName, version, dir string) {
-	plugin, ok := registry.Registry[toolName]
-	if !ok {
-		fmt.Printf("Error: Tool '%s' not found in registry\n", toolName)
-		os.Exit(1)
+func runInstall(w *output.Writer, toolName, version, dir string, flags installFlags) {
+	plugin := lookupTool(w, toolName)
+	reporter := flags.reporter(w)
+
+	client := newClient(w, 0, flags.waitOnRateLimit, flags.offline)
+	client.Progress = reporter
+	opts := installer.Options{
+		Client:           client,
+		RequireChecksum:  flags.requireChecksum,
+		RequireSignature: flags.requireSignature,
+		LockTimeout:      flags.lockTimeout,
+		Progress:         reporter,
+		ShareDir:         flags.shareDir,
+	}
+
+	var resolved *source.Release
+	if registry.IsVersionSpec(version) {
+		release, err := plugin.Resolve(source.Open(client, plugin.Source), version)
+		if err != nil {
+			fail(w, errorCode(err, output.CodeFetchFailed), toolName, fmt.Errorf("failed to resolve %s@%s: %w", toolName, version, err))
+		}
+		resolved = &release
+		display := plugin.GetDisplayVersion(release.TagName)
+		reporter.Logf("Resolved %s to %s (tag: %s)", version, display, release.TagName)
+		version = display
+	}
+
+	downloads, err := cache.OpenDownloads()
+	if err != nil {
+		reporter.Logf("Warning: download cache disabled: %v", err)
+	} else {
+		opts.Downloads = downloads
+	}
+
+	result, err := installer.Install(plugin, version, dir, opts)
+	if err != nil {
+		fail(w, errorCode(err, output.CodeInstallFailed), toolName, fmt.Errorf("failed to install %s@%s: %w", toolName, version, err))
+	}
+
+	if !w.Format().Structured() {
+		reporter.Logf("Successfully installed %s to %s", toolName, dir)
+		return
+	}
+
+	release := result.Release
+	if release == nil {
+		release = resolved
+	}
+	record := installRecord{
+		versionRecord:  versionRecord{Tool: toolName, Version: version, Tag: result.Tag},
+		Asset:          result.Asset,
+		DownloadURL:    result.DownloadURL,
+		Checksum:       result.Checksum,
+		ChecksumStatus: result.ChecksumStatus,
+		InstallPath:    result.InstallPath,
+	}
+	if release != nil {
+		record.PublishedAt = release.PublishedAt
+		record.Prerelease = release.Prerelease
 	}
+	w.Object(record)
+}
 
-	err := installer.Install(plugin, version, dir)
+func runRateLimit(w *output.Writer) {
+	client := newClient(w, 0, false, false)
+	limits, err := client.GetRateLimits()
 	if err != nil {
-		fmt.Printf("Error installing %s@%s: %v\n", toolName, version, err)
-		os.Exit(1)
+		fail(w, errorCode(err, output.CodeFetchFailed), "", fmt.Errorf("failed to fetch rate limits: %w", err))
 	}
 
-	fmt.Printf("Successfully installed %s to %s\n", toolName, dir)
+	// The core limit covers every request sous-chef makes, so show it first
+	sort.Slice(limits, func(i, j int) bool {
+		if (limits[i].Resource == "core") != (limits[j].Resource == "core") {
+			return limits[i].Resource == "core"
+		}
+		return limits[i].Resource < limits[j].Resource
+	})
+
+	if !w.Format().Structured() {
+		printCredential(&source.GitHub{Client: client})
+	}
+	for _, l := range limits {
+		if w.Format().Structured() {
+			w.Record(l)
+		} else {
+			fmt.Printf("%s: %s\n", l.Resource, l)
+		}
+	}
+	w.Flush()
+}
+
+// runAuth reports which credential source is used for the default host and
+// every other host in the registry, never the token itself
+func runAuth(w *output.Writer) {
+	client := newClient(w, 0, false, false)
+	printCredential(&source.GitHub{Client: client})
+
+	seen := map[string]bool{client.Host(): true}
+	reg := loadRegistry(w)
+	names := make([]string, 0, len(reg))
+	for name := range reg {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		src := source.Open(client, reg[name].Source)
+		if !seen[src.Host()] {
+			seen[src.Host()] = true
+			printCredential(src)
+		}
+	}
+}
+
+func printCredential(src source.Source) {
+	if cred, ok := src.Credential(); ok {
+		fmt.Printf("%s: token from %s\n", src.Host(), cred.Source)
+	} else {
+		fmt.Printf("%s: no token found, requests are unauthenticated\n", src.Host())
+	}
+}
+
+func runCache(w *output.Writer, action string) {
+	switch action {
+	case "info":
+		dir, err := cache.Dir()
+		if err != nil {
+			fail(w, output.CodeError, "", err)
+		}
+		usage, err := cache.Info()
+		if err != nil {
+			fail(w, output.CodeError, "", fmt.Errorf("failed to read cache: %w", err))
+		}
+
+		fmt.Printf("Cache directory: %s\n", dir)
+		for _, u := range usage {
+			fmt.Printf("  %s: %d files, %s\n", filepath.Base(u.Path), u.Entries, progress.FormatBytes(u.Bytes))
+		}
+
+	case "clear":
+		if err := cache.Clear(); err != nil {
+			fail(w, output.CodeError, "", fmt.Errorf("failed to clear cache: %w", err))
+		}
+		fmt.Println("Cache cleared.")
+
+	default:
+		printUsage()
+		os.Exit(output.ExitUsage)
+	}
 }
@@ -1,6 +1,6 @@
 PLUGIN = {
   name = "sous-chef",
-  version = "0.0.5",
+  version = "0.1.0",
   description = "A high-performance meta-plugin backend engine for mise (Go version)",
   author = "aniaan",
   repo = "https://github.com/aniaan/sous-chef",